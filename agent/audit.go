@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/store"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditLedger holds the append-only log of issuances and the last entry of each exchange
+type auditLedger struct {
+	log  *store.Log
+	mu   *sync.Mutex
+	seq  int64
+	last map[string]models.AuditEntry // credential exchange ID to the last audit entry of the exchange
+	head string                       // hash of the last entry of the ledger
+	key  []byte
+}
+
+func newAuditLedger(dataDir string) *auditLedger {
+	return &auditLedger{log: store.NewLog(dataDir, `issuance-audit.log`), mu: &sync.Mutex{}, last: make(map[string]models.AuditEntry)}
+}
+
+// auditKeySize is the size in bytes of generated audit keys and the minimum size of given keys
+const auditKeySize = 32
+
+// credential exchange states (v2.0) which are recorded in the audit ledger
+var auditOutcomes = map[string]string{
+	`credential-issued`:  models.AuditOutcomeIssued,
+	`done`:               models.AuditOutcomeStored,
+	`credential-revoked`: models.AuditOutcomeRevoked,
+	`abandoned`:          models.AuditOutcomeAbandoned,
+}
+
+// RecordIssuance appends an entry to the audit ledger if the state of the issuer's credential exchange is an
+// auditable outcome. Other states are ignored.
+func (a *Agent) RecordIssuance(ev models.IssuanceEvent) error {
+	outcome, ok := auditOutcomes[ev.State]
+	if !ok {
+		return nil
+	}
+
+	label, _ := a.GetLabelByConnection(ev.ConnectionID)
+
+	// the previous entry is read under the same lock as the append so that entries of an exchange are chained in order
+	a.audit.mu.Lock()
+	defer a.audit.mu.Unlock()
+
+	var attrs []models.AuditAttribute
+	for _, attr := range ev.Attributes {
+		attrs = append(attrs, models.AuditAttribute{Name: attr.Name, Hash: a.hashAttribute(attr.Name, attr.Value)})
+	}
+
+	// revocations and completions may not carry the preview, hence it is taken from the previous entry of the exchange
+	if prev, ok := a.audit.last[ev.CredExID]; ok {
+		if ev.CredDefID == `` {
+			ev.CredDefID = prev.CredDefID
+		}
+		if ev.ConnectionID == `` {
+			ev.ConnectionID = prev.ConnectionID
+		}
+		if label == `` {
+			label = prev.HolderLabel
+		}
+		if len(attrs) == 0 {
+			attrs = prev.Attributes
+		}
+	}
+
+	entry := models.AuditEntry{
+		Seq:          a.audit.seq + 1,
+		CredExID:     ev.CredExID,
+		ConnectionID: ev.ConnectionID,
+		HolderLabel:  label,
+		CredDefID:    ev.CredDefID,
+		Attributes:   attrs,
+		Outcome:      outcome,
+		State:        ev.State,
+		CreatedAt:    ev.CreatedAt,
+		UpdatedAt:    ev.UpdatedAt,
+		RecordedAt:   time.Now().UTC(),
+		PrevHash:     a.audit.head,
+	}
+
+	var err error
+	if entry.Hash, err = a.chainHash(entry); err != nil {
+		return err
+	}
+
+	if err = a.audit.log.Append(entry); err != nil {
+		return fmt.Errorf(`append audit entry - %v`, err)
+	}
+
+	a.audit.seq, a.audit.head = entry.Seq, entry.Hash
+	a.audit.last[entry.CredExID] = entry
+	a.logger.Debug("audit entry recorded", entry.Seq, entry.CredExID, entry.Outcome)
+	return nil
+}
+
+// AuditEntries returns the entries of the audit ledger matching the filter in the order they were recorded
+func (a *Agent) AuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := a.audit.log.Read(func(line []byte) error {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf(`unmarshal error - %v [%s]`, err, string(line))
+		}
+
+		if matchAuditEntry(entry, filter) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(`read audit ledger - %v`, err)
+	}
+
+	return entries, nil
+}
+
+// VerifyAuditLedger recomputes the hash chain of the ledger and reports the first entry which does not match
+func (a *Agent) VerifyAuditLedger() (models.AuditVerification, error) {
+	res := models.AuditVerification{Valid: true}
+	var prev models.AuditEntry
+	err := a.audit.log.Read(func(line []byte) error {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf(`unmarshal error - %v [%s]`, err, string(line))
+		}
+
+		res.Entries++
+		if !res.Valid {
+			return nil
+		}
+
+		hash, err := a.chainHash(entry)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.Seq != prev.Seq+1:
+			res.Reason = fmt.Sprintf(`entry %d follows entry %d`, entry.Seq, prev.Seq)
+		case entry.PrevHash != prev.Hash:
+			res.Reason = `previous hash does not match the previous entry`
+		case !hmac.Equal([]byte(hash), []byte(entry.Hash)):
+			res.Reason = `hash does not match the content of the entry`
+		default:
+			prev, res.LastHash = entry, entry.Hash
+			return nil
+		}
+
+		res.Valid, res.BrokenAt = false, entry.Seq
+		return nil
+	})
+	if err != nil {
+		return models.AuditVerification{}, fmt.Errorf(`read audit ledger - %v`, err)
+	}
+
+	return res, nil
+}
+
+// loadAuditLedger restores the sequence and the head of the ledger and the last entry of each exchange
+func (a *Agent) loadAuditLedger() error {
+	return a.audit.log.Read(func(line []byte) error {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf(`unmarshal error - %v [%s]`, err, string(line))
+		}
+
+		if entry.Seq > a.audit.seq {
+			a.audit.seq, a.audit.head = entry.Seq, entry.Hash
+		}
+		a.audit.last[entry.CredExID] = entry
+		return nil
+	})
+}
+
+// chainHash computes the HMAC of the entry along with the hash of the previous entry it refers to
+func (a *Agent) chainHash(entry models.AuditEntry) (string, error) {
+	entry.Hash = ``
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return ``, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	h := hmac.New(sha256.New, a.audit.key)
+	h.Write([]byte(entry.PrevHash))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadAuditKey reads the hex encoded audit key from the file, or generates and stores a new key if the file does not
+// exist yet
+func loadAuditKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) < auditKeySize {
+			return nil, fmt.Errorf(`audit key in %s should be at least %d hex encoded bytes`, path, auditKeySize)
+		}
+		return key, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf(`reading key file - %v`, err)
+	}
+
+	key := make([]byte, auditKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, fmt.Errorf(`generating key - %v`, err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf(`creating directory - %v`, err)
+	}
+
+	if err = ioutil.WriteFile(path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		return nil, fmt.Errorf(`writing key file - %v`, err)
+	}
+
+	return key, nil
+}
+
+func matchAuditEntry(entry models.AuditEntry, filter models.AuditFilter) bool {
+	if filter.CredExID != `` && filter.CredExID != entry.CredExID {
+		return false
+	}
+
+	if filter.HolderLabel != `` && filter.HolderLabel != entry.HolderLabel {
+		return false
+	}
+
+	if filter.CredDefID != `` && filter.CredDefID != entry.CredDefID {
+		return false
+	}
+
+	if filter.Outcome != `` && filter.Outcome != entry.Outcome {
+		return false
+	}
+
+	if !filter.From.IsZero() && entry.RecordedAt.Before(filter.From) {
+		return false
+	}
+
+	if !filter.To.IsZero() && entry.RecordedAt.After(filter.To) {
+		return false
+	}
+
+	return true
+}
+
+func (a *Agent) hashAttribute(name, value string) string {
+	h := hmac.New(sha256.New, a.audit.key)
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
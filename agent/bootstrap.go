@@ -0,0 +1,269 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/requests"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/domain"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	endpointPublicDid = `/wallet/did/public`
+	defaultCredDefTag = `default`
+)
+
+// EnsureSchema returns the ID of the schema created by this agent with the same name and version, and creates the
+// schema only if it does not exist
+func (a *Agent) EnsureSchema(schema domain.Schema) (schemaID string, created bool, err error) {
+	if err = schema.Validate(); err != nil {
+		return ``, false, fmt.Errorf(`invalid schema - %v`, err)
+	}
+
+	ids, err := a.CreatedSchemas(schema.SchemaName, schema.SchemaVersion)
+	if err != nil {
+		return ``, false, fmt.Errorf(`fetch created schemas - %v`, err)
+	}
+
+	if len(ids) > 0 {
+		existing, err := a.Schema(ids[0])
+		if err != nil {
+			return ``, false, fmt.Errorf(`fetch schema - %v`, err)
+		}
+
+		if !sameAttributes(existing.AttrNames, schema.Attributes) {
+			return ``, false, fmt.Errorf(`schema %s already exists with different attributes %v`, ids[0], existing.AttrNames)
+		}
+
+		a.logger.Debug("schema already exists", ids[0])
+		return ids[0], false, nil
+	}
+
+	res, err := a.CreateSchema(schema)
+	if err != nil {
+		return ``, false, fmt.Errorf(`create schema - %v`, err)
+	}
+
+	var s responses.Schema
+	if err = json.Unmarshal(res, &s); err != nil {
+		return ``, false, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(res))
+	}
+
+	return s.SchemaID, true, nil
+}
+
+// EnsureCredentialDef ensures the schema (if provided) and the credential definition with the tag exist for the
+// public DID of this agent, creating only the missing ones, and returns the identifiers to be used in offers
+func (a *Agent) EnsureCredentialDef(setup domain.CredentialDefinitionSetup) (models.EnsureResult, error) {
+	return a.ensureCredentialDef(setup, ``)
+}
+
+// ensureCredentialDef is similar to EnsureCredentialDef and records the given predecessor of the schema in the
+// lineage of its family if the schema is created by this agent
+func (a *Agent) ensureCredentialDef(setup domain.CredentialDefinitionSetup, previousSchemaID string) (models.EnsureResult, error) {
+	var result models.EnsureResult
+	if setup.Tag == `` {
+		setup.Tag = defaultCredDefTag
+	}
+
+	schemaID := setup.SchemaID
+	if setup.Schema != nil {
+		id, created, err := a.EnsureSchema(*setup.Schema)
+		if err != nil {
+			return result, fmt.Errorf(`ensure schema - %v`, err)
+		}
+
+		if schemaID != `` && schemaID != id {
+			return result, fmt.Errorf(`given schema ID %s does not match the schema %s`, schemaID, id)
+		}
+		schemaID, result.SchemaCreated = id, created
+	}
+
+	if schemaID == `` {
+		return result, fmt.Errorf(`either schema ID or schema should be provided`)
+	}
+
+	schema, err := a.Schema(schemaID)
+	if err != nil {
+		return result, fmt.Errorf(`fetch schema - %v`, err)
+	}
+
+	did, err := a.publicDid()
+	if err != nil {
+		return result, fmt.Errorf(`fetch public DID - %v`, err)
+	}
+
+	credDefID, err := a.createdCredentialDef(schemaID, did, setup.Tag)
+	if err != nil {
+		return result, fmt.Errorf(`fetch created credential definitions - %v`, err)
+	}
+
+	if credDefID == `` {
+		credDefID, err = a.createCredentialDef(requests.CredentialDef{
+			SchemaID:               schemaID,
+			Tag:                    setup.Tag,
+			SupportRevocation:      setup.SupportRevocation,
+			RevocationRegistrySize: setup.RevocationRegistrySize,
+		})
+		if err != nil {
+			return result, fmt.Errorf(`create credential definition - %v`, err)
+		}
+		result.CredDefCreated = true
+	}
+
+	result.Meta = domain.IndySchemaMeta{
+		CredDefID:       credDefID,
+		IssuerDid:       did,
+		SchemaID:        schemaID,
+		SchemaIssuerDid: strings.Split(schemaID, `:`)[0],
+		SchemaName:      schema.Name,
+		SchemaVersion:   schema.Version,
+	}
+
+	// versions of schemas created by this agent are recorded in the lineage of their families
+	if result.Meta.SchemaIssuerDid == did {
+		if err = a.recordSchemaVersion(models.SchemaVersion{Meta: result.Meta, Attributes: schema.AttrNames, PreviousSchemaID: previousSchemaID}); err != nil {
+			return result, err
+		}
+	}
+
+	a.logger.Debug("credential definition ensured", result)
+	return result, nil
+}
+
+// Bootstrap ensures all credential definitions (and their schemas) declared in the JSON file at the given path.
+// The results are kept so that the identifiers can be queried later.
+func (a *Agent) Bootstrap(path string) ([]models.EnsureResult, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(`reading bootstrap file - %v`, err)
+	}
+
+	var setups []domain.CredentialDefinitionSetup
+	if err = json.Unmarshal(data, &setups); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, path)
+	}
+
+	// credential definitions can only be created once the schema transactions are written to the ledger
+	if _, ok := a.Endorser(); ok && a.endorsement.timeout <= 0 {
+		return nil, fmt.Errorf(`bootstrapping with an endorser requires an endorsement timeout to await schema transactions`)
+	}
+
+	var results []models.EnsureResult
+	for i, setup := range setups {
+		res, err := a.EnsureCredentialDef(setup)
+		if err != nil {
+			return nil, fmt.Errorf(`entry %d of bootstrap file - %v`, i, err)
+		}
+
+		a.logger.Info(fmt.Sprintf(`bootstrapped credential definition %s of schema %s [schema created: %t, credential definition created: %t]`,
+			res.Meta.CredDefID, res.Meta.SchemaID, res.SchemaCreated, res.CredDefCreated))
+		results = append(results, res)
+	}
+
+	a.bootstrapped = results
+	return results, nil
+}
+
+// Bootstrapped returns the results of the last bootstrap
+func (a *Agent) Bootstrapped() []models.EnsureResult {
+	return a.bootstrapped
+}
+
+func (a *Agent) createdCredentialDef(schemaID, issuerDid, tag string) (string, error) {
+	params := url.Values{}
+	params.Add(`schema_id`, schemaID)
+	params.Add(`issuer_did`, issuerDid)
+
+	data, err := a.get(a.adminUrl+endpointCredDef+`/created?`+params.Encode(), fmt.Sprintf(`fetched created credential definitions of %s`, schemaID))
+	if err != nil {
+		return ``, err
+	}
+
+	var res responses.CreatedCredentialDefs
+	if err = json.Unmarshal(data, &res); err != nil {
+		return ``, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	for _, id := range res.CredentialDefinitionIDs {
+		if t, ok := credDefTag(id); ok && t == tag {
+			return id, nil
+		}
+	}
+
+	return ``, nil
+}
+
+// credDefTag parses the tag of a credential definition ID (<issuer_did>:3:CL:<schema_seq_no>:<tag>), which is the
+// remainder of the ID after the fourth separator since tags may contain separators as well
+func credDefTag(id string) (string, bool) {
+	parts := strings.SplitN(id, `:`, 5)
+	if len(parts) != 5 || parts[1] != `3` || parts[2] != `CL` {
+		return ``, false
+	}
+
+	return parts[4], true
+}
+
+func (a *Agent) createCredentialDef(req requests.CredentialDef) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return ``, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	res, err := a.CreateCredentialDef(data)
+	if err != nil {
+		return ``, err
+	}
+
+	var def responses.CredentialDef
+	if err = json.Unmarshal(res, &def); err != nil {
+		return ``, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(res))
+	}
+
+	return def.CredentialDefinitionID, nil
+}
+
+func (a *Agent) publicDid() (string, error) {
+	data, err := a.get(a.adminUrl+endpointPublicDid, `fetched public DID`)
+	if err != nil {
+		return ``, err
+	}
+
+	var res responses.PublicDid
+	if err = json.Unmarshal(data, &res); err != nil {
+		return ``, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	if res.Result.Did == `` {
+		return ``, fmt.Errorf(`agent does not have a public DID`)
+	}
+
+	return res.Result.Did, nil
+}
+
+func sameAttributes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	ca, cb := make([]string, len(a)), make([]string, len(b))
+	for i := range a {
+		ca[i], cb[i] = domain.CanonicalAttribute(a[i]), domain.CanonicalAttribute(b[i])
+	}
+	sort.Strings(ca)
+	sort.Strings(cb)
+
+	for i := range ca {
+		if ca[i] != cb[i] {
+			return false
+		}
+	}
+
+	return true
+}
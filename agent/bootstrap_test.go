@@ -0,0 +1,28 @@
+package agent
+
+import "testing"
+
+func TestCredDefTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		want   string
+		wantOK bool
+	}{
+		{name: `default tag`, id: `Th7MpTaRZVRYnPiabds81Y:3:CL:12:default`, want: `default`, wantOK: true},
+		{name: `tag with separators`, id: `Th7MpTaRZVRYnPiabds81Y:3:CL:12:v1:revocable`, want: `v1:revocable`, wantOK: true},
+		{name: `empty tag`, id: `Th7MpTaRZVRYnPiabds81Y:3:CL:12:`, want: ``, wantOK: true},
+		{name: `missing tag`, id: `Th7MpTaRZVRYnPiabds81Y:3:CL:12`, wantOK: false},
+		{name: `schema ID`, id: `WgWxqztrNooG92RXvxSTWv:2:degree:1.0`, wantOK: false},
+		{name: `other signature type`, id: `Th7MpTaRZVRYnPiabds81Y:3:BBS:12:default`, wantOK: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := credDefTag(tc.id)
+			if ok != tc.wantOK || got != tc.want {
+				t.Errorf(`got (%s, %t), want (%s, %t)`, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
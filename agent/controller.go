@@ -11,50 +11,181 @@ import (
 	"github.com/tryfix/log"
 	"io/ioutil"
 	"net/http"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
 // agent endpoints
 const (
-	endpointCreateInv    = `/connections/create-invitation`
-	endpointAcceptInv    = `/connections/receive-invitation`
-	endpointConn         = `/connections/`
-	endpointSchemas      = `/schemas`
-	endpointCredDef      = `/credential-definitions`
-	endpointSendOffer    = `/issue-credential-2.0/send-offer`
-	endpointSendCredAuto = `/issue-credential-2.0/send`
-	endpointCredRecords  = `/issue-credential-2.0/records/`
-	endpointSendProofReq = `/present-proof-2.0/send-request`
-	endpointProofRecords = `/present-proof-2.0/records/`
-	endpointCredentials  = `/credentials`
+	endpointCreateInv       = `/connections/create-invitation`
+	endpointAcceptInv       = `/connections/receive-invitation`
+	endpointConn            = `/connections/`
+	endpointSchemas         = `/schemas`
+	endpointCredDef         = `/credential-definitions`
+	endpointSendOffer       = `/issue-credential-2.0/send-offer`
+	endpointSendCredAuto    = `/issue-credential-2.0/send`
+	endpointCredRecords     = `/issue-credential-2.0/records/`
+	endpointCredRecordList  = `/issue-credential-2.0/records`
+	endpointSendProofReq    = `/present-proof-2.0/send-request`
+	endpointProofRecords    = `/present-proof-2.0/records/`
+	endpointProofRecordList = `/present-proof-2.0/records`
+	endpointCreateOffer     = `/issue-credential-2.0/create-offer`
+	endpointCreateProofReq  = `/present-proof-2.0/create-request`
+	endpointCreateOOBInv    = `/out-of-band/create-invitation`
 )
 
+type Config struct {
+	Name               string
+	AdminUrl           string
+	DataDir            string        // directory in which the controller persists its own state
+	AutoRemove         bool          // default auto_remove of credential offers
+	RecordRetention    time.Duration // retention of completed and abandoned exchange records
+	SweepInterval      time.Duration
+	CredentialTTL      time.Duration // time within which a peer should answer a credential offer
+	ProofTTL           time.Duration // time within which a peer should answer a proof request
+	EndorsementTimeout time.Duration // time to wait for endorsed writes (zero does not wait)
+	AutoWriteTxns      bool          // set if ACA-Py writes endorsed transactions itself
+	CallbackHosts      []string      // hosts to which verification records may be posted
+	AuditKeyFile       string        // hex encoded key of the audit ledger (generated in DataDir if empty)
+	AllowUnrevealed    bool          // present attributes unrevealed even if the verifier does not mark them
+}
+
 type Agent struct {
-	name     string
-	adminUrl string
-	client   *http.Client
-	logger   log.Logger
-	connMap  *sync.Map // peer agent label to own connection ID map
-	credMap  *sync.Map // peer agent label to credential exchange ID map
-	proofMap *sync.Map // peer agent label to proof exchange ID map
+	name         string
+	adminUrl     string
+	client       *http.Client
+	logger       log.Logger
+	connMap      *sync.Map // peer agent label to own connection ID map
+	credMap      *sync.Map // peer agent label to credential exchange ID map
+	proofMap     *sync.Map // peer agent label to proof exchange ID map
+	schemaCache  *sync.Map // schema ID to ledger schema map
+	bootstrapped []models.EnsureResult
+	templates    *templates
+	audit        *auditLedger
+	retention    *retention
+	expiry       *expiry
+	oob          *connectionless
+	versions     *versions
+	endorsement  *endorsement
+	lineage      *lineage
+	selection    *selection
+	verification *verification
 }
 
-func New(name string, adminUrl string, logger log.Logger) *Agent {
-	return &Agent{
-		name:     name,
-		adminUrl: adminUrl,
-		client:   &http.Client{},
-		logger:   logger,
-		connMap:  &sync.Map{},
-		credMap:  &sync.Map{},
-		proofMap: &sync.Map{},
+func New(cfg Config, logger log.Logger) (*Agent, error) {
+	a := &Agent{
+		name:         cfg.Name,
+		adminUrl:     cfg.AdminUrl,
+		client:       &http.Client{},
+		logger:       logger,
+		connMap:      &sync.Map{},
+		credMap:      &sync.Map{},
+		proofMap:     &sync.Map{},
+		schemaCache:  &sync.Map{},
+		templates:    newTemplates(cfg.DataDir),
+		audit:        newAuditLedger(cfg.DataDir),
+		retention:    newRetention(cfg),
+		expiry:       newExpiry(cfg),
+		oob:          newConnectionless(cfg.DataDir),
+		versions:     newVersions(cfg.DataDir),
+		endorsement:  newEndorsement(cfg),
+		lineage:      newLineage(cfg.DataDir),
+		selection:    newSelection(cfg),
+		verification: newVerification(cfg),
+	}
+
+	if err := a.loadOfferTemplates(); err != nil {
+		return nil, fmt.Errorf(`load offer templates - %v`, err)
+	}
+
+	if err := a.loadProofTemplates(); err != nil {
+		return nil, fmt.Errorf(`load proof templates - %v`, err)
+	}
+
+	if err := a.loadAuditLedger(); err != nil {
+		return nil, fmt.Errorf(`load audit ledger - %v`, err)
+	}
+
+	keyFile := cfg.AuditKeyFile
+	if keyFile == `` {
+		keyFile = filepath.Join(cfg.DataDir, `audit.key`)
+	}
+
+	var err error
+	if a.audit.key, err = loadAuditKey(keyFile); err != nil {
+		return nil, fmt.Errorf(`load audit key - %v`, err)
+	}
+
+	if err := a.retention.store.Load(&a.retention.overrides); err != nil {
+		return nil, fmt.Errorf(`load retention overrides - %v`, err)
+	}
+
+	if err := a.versions.store.Load(&a.versions.conns); err != nil {
+		return nil, fmt.Errorf(`load protocol versions - %v`, err)
+	}
+
+	if err := a.expiry.store.Load(&a.expiry.pending); err != nil {
+		return nil, fmt.Errorf(`load pending exchanges - %v`, err)
 	}
+
+	if err := a.oob.store.Load(&a.oob.exchanges); err != nil {
+		return nil, fmt.Errorf(`load connectionless exchanges - %v`, err)
+	}
+
+	if err := a.endorsement.store.Load(&a.endorsement.endorser); err != nil {
+		return nil, fmt.Errorf(`load endorser - %v`, err)
+	}
+
+	if err := a.endorsement.txnStore.Load(&a.endorsement.txns); err != nil {
+		return nil, fmt.Errorf(`load transactions - %v`, err)
+	}
+
+	if err := a.endorsement.policyStore.Load(&a.endorsement.policy); err != nil {
+		return nil, fmt.Errorf(`load endorsement policy - %v`, err)
+	}
+
+	if err := a.lineage.store.Load(&a.lineage.families); err != nil {
+		return nil, fmt.Errorf(`load schema lineage - %v`, err)
+	}
+
+	if err := a.selection.store.Load(&a.selection.policy); err != nil {
+		return nil, fmt.Errorf(`load selection policy - %v`, err)
+	}
+
+	if err := a.loadStoredCredentials(); err != nil {
+		return nil, fmt.Errorf(`load stored credentials - %v`, err)
+	}
+
+	if err := a.verification.store.Load(&a.verification.records); err != nil {
+		return nil, fmt.Errorf(`load verifications - %v`, err)
+	}
+
+	return a, nil
 }
 
 func (a *Agent) AddConnection(label, connID string) {
 	a.connMap.Store(label, connID)
 }
 
+// GetLabelByConnection returns the peer agent label of the given connection ID
+func (a *Agent) GetLabelByConnection(connID string) (string, error) {
+	var label string
+	a.connMap.Range(func(key, val interface{}) bool {
+		if val == connID {
+			label, _ = key.(string)
+			return false
+		}
+		return true
+	})
+
+	if label == `` {
+		return ``, fmt.Errorf(`no recipient found for the connection %s`, connID)
+	}
+
+	return label, nil
+}
+
 func (a *Agent) GetConnectionByLabel(label string) (string, error) {
 	val, ok := a.connMap.Load(label)
 	if !ok {
@@ -235,36 +366,17 @@ func (a *Agent) Connection(connID string) (response []byte, err error) {
 	return a.get(a.adminUrl+endpointConn+connID, fmt.Sprintf("connection fetched %s", connID))
 }
 
-// CreateSchema forwards the received schema directly to the agent (needs to be a Trust Anchor)
-func (a *Agent) CreateSchema(schema []byte) (response []byte, err error) {
-	return a.post(a.adminUrl+endpointSchemas, schema, "schema created")
-}
-
 // CreateCredentialDef forwards the received credential definition body directly to the agent to persist on ledger
+// (via the endorser if configured)
 func (a *Agent) CreateCredentialDef(def []byte) (response []byte, err error) {
-	return a.post(a.adminUrl+endpointCredDef, def, "credential definition created")
+	return a.postLedgerWrite(endpointCredDef, def, models.TxnTypeCredDef, "credential definition created")
 }
 
 // SendCredentialOffer takes domain.CredentialPreview and domain.IndySchemaMeta along with the recipient label which will then be
 // used to send a credential offer to the (to-be) holder. Setting auto_remove of offer to true removes credential exchange
 // record automatically after the protocol completes.
-func (a *Agent) SendCredentialOffer(cp domain.CredentialPreview, indySchema domain.IndySchemaMeta, to string) (response []byte, err error) {
-	req := requests.Offer{}
-	req.CredentialPreview = cp
-	req.Filter.Indy = indySchema
-	req.Comment = a.name
-	req.ConnectionID, err = a.GetConnectionByLabel(to)
-	if err != nil {
-		return nil, fmt.Errorf(`get connection by label - %v`, err)
-	}
-	a.logger.Debug("credential offer constructed", req)
-
-	data, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf(`marshal error - %v`, err)
-	}
-
-	return a.post(a.adminUrl+endpointSendOffer, data, fmt.Sprintf("offer sent to %s", to))
+func (a *Agent) SendCredentialOffer(cp domain.CredentialPreview, indySchema domain.IndySchemaMeta, to string, opts models.OfferOptions) (response []byte, err error) {
+	return a.sendOffer(false, cp, indySchema, a.name, to, opts)
 }
 
 // CredentialRecord finds the corresponding credential exchange ID from the in-memory map and fetches the credential record from the ledger
@@ -279,141 +391,285 @@ func (a *Agent) CredentialRecord(label string) (response []byte, err error) {
 		return nil, fmt.Errorf(`incompatible credential exchange ID found for label %s [%v]`, label, val)
 	}
 
-	return a.get(a.adminUrl+endpointCredRecords+credExID, fmt.Sprintf("credential record fetched with id %s", credExID))
+	_, p := a.exchangeProtocol(credExID)
+	res, err := a.get(a.adminUrl+p.credRecords+credExID, fmt.Sprintf("credential record fetched with id %s", credExID))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.withExpiry(credExID, res)
 }
 
 // RequestCredential proceeds with requesting the credential from the issuer which corresponds to the given credential exchange ID
 func (a *Agent) RequestCredential(credExID string) (response []byte, err error) {
-	return a.post(a.adminUrl+endpointCredRecords+credExID+`/send-request`, nil, fmt.Sprintf("requested credential with id %s", credExID))
+	_, p := a.exchangeProtocol(credExID)
+	return a.post(a.adminUrl+p.credRecords+credExID+`/send-request`, nil, fmt.Sprintf("requested credential with id %s", credExID))
 }
 
 // IssueCredential proceeds with issuing the credential to the holder via connected agent
 func (a *Agent) IssueCredential(credExID string) (response []byte, err error) {
 	body := `{"comment": "issuing credential"}`
-	return a.post(a.adminUrl+endpointCredRecords+credExID+`/issue`, []byte(body), fmt.Sprintf("issued credential with id %s", credExID))
+	_, p := a.exchangeProtocol(credExID)
+	return a.post(a.adminUrl+p.credRecords+credExID+`/issue`, []byte(body), fmt.Sprintf("issued credential with id %s", credExID))
 }
 
 // StoreCredential fetches the credential record by the given ID and stores it in the wallet of the holder. If user needs to fetch
 // this stored credential directly from the wallet, id corresponding to `cred_id_stored` parameter of this response should be used.
 func (a *Agent) StoreCredential(credExID string) (response []byte, err error) {
-	return a.post(a.adminUrl+endpointCredRecords+credExID+`/store`, nil, fmt.Sprintf("stored credential with id %s", credExID))
+	_, p := a.exchangeProtocol(credExID)
+	return a.post(a.adminUrl+p.credRecords+credExID+`/store`, nil, fmt.Sprintf("stored credential with id %s", credExID))
 }
 
 // SendCredentialAuto starts from sending an offer for a credential and follows an automated process for the rest of the steps.
 // This needs the holder to enable auto-responsiveness to credential offers.
-func (a *Agent) SendCredentialAuto(cp domain.CredentialPreview, indySchema domain.IndySchemaMeta, to string) (response []byte, err error) {
-	req := requests.Offer{}
-	req.CredentialPreview = cp
-	req.Filter.Indy = indySchema
-	req.Comment = a.name
-	req.ConnectionID, err = a.GetConnectionByLabel(to)
+func (a *Agent) SendCredentialAuto(cp domain.CredentialPreview, indySchema domain.IndySchemaMeta, to string, opts models.OfferOptions) (response []byte, err error) {
+	return a.sendOffer(true, cp, indySchema, a.name, to, opts)
+}
+
+// SendTemplateOffer constructs the credential offer from the stored template and the given attribute values, and
+// sends it to the recipient either as a plain offer or as an automated process
+func (a *Agent) SendTemplateOffer(name string, values map[string]string, to string, auto bool, opts models.OfferOptions) (response []byte, err error) {
+	cp, filter, comment, err := a.templateOffer(name, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.sendOffer(auto, cp, filter, comment, to, opts)
+}
+
+// sendOffer sends the offer in the issue-credential version used with the recipient, either as a plain offer or as
+// an automated process
+func (a *Agent) sendOffer(auto bool, cp domain.CredentialPreview, indySchema domain.IndySchemaMeta, comment, to string, opts models.OfferOptions) (response []byte, err error) {
+	connID, err := a.GetConnectionByLabel(to)
 	if err != nil {
 		return nil, fmt.Errorf(`get connection by label - %v`, err)
 	}
-	a.logger.Debug("credential offer constructed for automated process", req)
+
+	autoRemove := a.retention.autoRemove
+	if opts.AutoRemove != nil {
+		autoRemove = *opts.AutoRemove
+	}
+
+	version := a.connProtocols(connID).IssueCredential
+	p := protocols[version]
+	endpoint := p.sendOffer
+	if auto {
+		endpoint = p.sendCredAuto
+	}
+
+	var req interface{}
+	switch {
+	case version == ProtocolV1 && auto:
+		cp.Type = credentialPreviewTypeV1
+		req = requests.ProposalV1{
+			AutoRemove:         autoRemove,
+			Comment:            comment,
+			ConnectionID:       connID,
+			CredDefID:          indySchema.CredDefID,
+			CredentialProposal: cp,
+			IssuerDid:          indySchema.IssuerDid,
+			SchemaID:           indySchema.SchemaID,
+			SchemaIssuerDid:    indySchema.SchemaIssuerDid,
+			SchemaName:         indySchema.SchemaName,
+			SchemaVersion:      indySchema.SchemaVersion,
+		}
+	case version == ProtocolV1:
+		cp.Type = credentialPreviewTypeV1
+		req = requests.OfferV1{AutoRemove: autoRemove, Comment: comment, ConnectionID: connID, CredDefID: indySchema.CredDefID, CredentialPreview: cp}
+	default:
+		offer := requests.Offer{AutoRemove: autoRemove, Comment: comment, ConnectionID: connID, CredentialPreview: cp}
+		offer.Filter.Indy = indySchema
+		req = offer
+	}
+	a.logger.Debug("credential offer constructed", version, req)
 
 	data, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf(`marshal error - %v`, err)
 	}
 
-	return a.post(a.adminUrl+endpointSendCredAuto, data, fmt.Sprintf("automated process started by sending offer to %s", to))
+	res, err := a.post(a.adminUrl+endpoint, data, fmt.Sprintf("offer sent to %s via %s", to, endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := parseExchangeRecord(models.ExchangeTypeCredential, version, res)
+	if err != nil {
+		return nil, err
+	}
+
+	a.SetExchangeVersion(rec.ID, version)
+	if opts.Retention != nil {
+		a.setRetention(rec.ID, *opts.Retention)
+	}
+	a.TrackExchange(models.ExchangeTypeCredential, rec.ID, `issuer`, to)
+
+	return res, nil
 }
 
-// SendProofRequest finds the corresponding connection ID of the peer agent label and sends a proof request with the given body
-func (a *Agent) SendProofRequest(pr domain.PresentationRequest, to string) (response []byte, err error) {
+// SendProofRequest finds the corresponding connection ID of the peer agent label and sends a proof request with the
+// given body, which is verified once received if auto verification is requested
+func (a *Agent) SendProofRequest(pr domain.PresentationRequest, to string, opts models.ProofRequestOptions) (response []byte, err error) {
+	if err = a.validateProofRequestOptions(opts); err != nil {
+		return nil, fmt.Errorf(`invalid options - %v`, err)
+	}
+
 	connID, err := a.GetConnectionByLabel(to)
 	if err != nil {
 		return nil, fmt.Errorf(`get connection by label - %v`, err)
 	}
 
-	req := requests.ProofRequest{Comment: a.name, ConnectionID: connID, PresentReq: pr}
+	if err = a.prepareProofRequest(&pr); err != nil {
+		return nil, err
+	}
+
+	var req interface{} = requests.ProofRequest{Comment: a.name, ConnectionID: connID, PresentReq: pr}
+	version := a.connProtocols(connID).PresentProof
+	if version == ProtocolV1 {
+		if pr.Indy == nil {
+			return nil, fmt.Errorf(`dif presentation requests are not supported by present-proof v1.0 used with %s`, to)
+		}
+		req = requests.ProofRequestV1{Comment: a.name, ConnectionID: connID, ProofRequest: *pr.Indy}
+	}
+
 	data, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf(`marshal error - %v`, err)
 	}
 
-	return a.post(a.adminUrl+endpointSendProofReq, data, fmt.Sprintf(`proof request received by %s`, to))
-}
+	res, err := a.post(a.adminUrl+protocols[version].sendProofReq, data, fmt.Sprintf(`proof request received by %s`, to))
+	if err != nil {
+		return nil, err
+	}
 
-// PresentProof sends the presentation format of the proof to a verifier given by the peer agent label. It first fetches
-// existing credentials from the holder's wallet and gets the corresponding record stored by the webhook. This is then
-// used to extract requested attributes by the verifier along with the existing credentials from the holder's proof in order
-// to construct the presentation proof.
-func (a *Agent) PresentProof(to string) (response []byte, err error) {
-	// todo only requested attributes atm
-	creds, err := a.getCredentialsFromWallet()
+	rec, err := parseExchangeRecord(models.ExchangeTypeProof, version, res)
 	if err != nil {
-		return nil, fmt.Errorf(`get credentials failed - %v`, err)
+		return nil, err
 	}
 
+	a.SetExchangeVersion(rec.ID, version)
+	a.TrackExchange(models.ExchangeTypeProof, rec.ID, `verifier`, to)
+	a.enableAutoVerify(rec.ID, opts)
+
+	return res, nil
+}
+
+// prepareProofRequest validates the request and resolves the schema families of indy requests
+func (a *Agent) prepareProofRequest(pr *domain.PresentationRequest) error {
+	if err := pr.Validate(); err != nil {
+		return fmt.Errorf(`invalid presentation request - %v`, err)
+	}
+
+	if pr.Indy == nil {
+		return nil
+	}
+
+	if err := a.resolveSchemaFamilies(pr.Indy); err != nil {
+		return fmt.Errorf(`resolve schema families - %v`, err)
+	}
+
+	return nil
+}
+
+// PresentProof sends the presentation of the proof to a verifier given by the peer agent label, selecting the
+// credentials of the request stored by the webhook as chosen by the holder or by the selection policy
+func (a *Agent) PresentProof(to string, pres domain.Presentation) (response []byte, err error) {
 	pp, err := a.GetPresentationRecord(to)
 	if err != nil {
 		return nil, fmt.Errorf(`get record failed - %v`, err)
 	}
-	proof := a.constructProof(pp.PresReq.Indy.RequestedAttributes, creds)
+
+	if pp.PresReq.Dif != nil {
+		proof, _, err := a.difPresentation(pp.PresExID, *pp.PresReq.Dif, pres)
+		if err != nil {
+			return nil, fmt.Errorf(`construct dif presentation - %v`, err)
+		}
+		return a.sendProofPresentation(pp.PresExID, proof)
+	}
+
+	if pp.PresReq.Indy == nil {
+		return nil, fmt.Errorf(`presentation request of exchange %s is in an unsupported format`, pp.PresExID)
+	}
+
+	proof, _, err := a.constructProof(pp.PresExID, *pp.PresReq.Indy, pres)
+	if err != nil {
+		return nil, fmt.Errorf(`construct proof - %v`, err)
+	}
 
 	return a.sendProofPresentation(pp.PresExID, proof)
 }
 
-// constructProof checks the values from the existing credentials in the wallet to match with requested attributes
-func (a *Agent) constructProof(reqAttributes map[string]domain.Attribute, creds []responses.WalletCredential) requests.ProofPresentation {
-	var proof requests.ProofPresentation
-	proof.Indy.RequestedAttributes = make(map[string]requests.AdditionalProp)
-	proof.Indy.RequestedPredicates = make(map[string]requests.AdditionalProp)
-	proof.Indy.SelfAttestedAttributes = make(map[string]string)
-attrLoop:
-	for reqAttrKey, reqAttr := range reqAttributes {
-		for _, cred := range creds {
-			for credAttrName, _ := range cred.Attrs {
-				// checks if stored attribute name and cred def ID are same as the requested
-				if reqAttr.Name == credAttrName {
-					for _, restrict := range reqAttr.Restrictions {
-						if restrict.CredDefID == cred.CredDefID {
-							proof.Indy.RequestedAttributes[reqAttrKey] = requests.AdditionalProp{CredID: cred.Referent, Revealed: true}
-							continue attrLoop
-						}
-					}
-				}
-			}
+// PreviewProof constructs the presentation to the verifier given by the peer agent label in the same way as
+// PresentProof without sending it, and returns what the verifier would learn from it
+func (a *Agent) PreviewProof(to string, pres domain.Presentation) (models.ProofPreview, error) {
+	pp, err := a.GetPresentationRecord(to)
+	if err != nil {
+		return models.ProofPreview{}, fmt.Errorf(`get record failed - %v`, err)
+	}
+
+	var preview models.ProofPreview
+	switch {
+	case pp.PresReq.Dif != nil:
+		if preview, err = a.previewDIF(pp.PresExID, *pp.PresReq.Dif, pres); err != nil {
+			return models.ProofPreview{}, fmt.Errorf(`construct dif presentation - %v`, err)
 		}
+	case pp.PresReq.Indy != nil:
+		if _, preview, err = a.constructProof(pp.PresExID, *pp.PresReq.Indy, pres); err != nil {
+			return models.ProofPreview{}, fmt.Errorf(`construct proof - %v`, err)
+		}
+	default:
+		return models.ProofPreview{}, fmt.Errorf(`presentation request of exchange %s is in an unsupported format`, pp.PresExID)
 	}
 
-	a.logger.Debug(`presentation proof constructed`, proof)
-	return proof
+	preview.Verifier = to
+	return preview, nil
 }
 
+// sendProofPresentation sends the presentation where v1.0 expects the indy proof at the top level of the body
 func (a *Agent) sendProofPresentation(presExID string, proofPres requests.ProofPresentation) (response []byte, err error) {
-	data, err := json.Marshal(proofPres)
+	version, p := a.exchangeProtocol(presExID)
+	var body interface{} = proofPres
+	if version == ProtocolV1 {
+		body = proofPres.Indy
+	}
+
+	data, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf(`marhsal error - %v`, err)
 	}
 
-	return a.post(a.adminUrl+endpointProofRecords+presExID+`/send-presentation`, data, fmt.Sprintf(`presentation sent with exchange id %s`, presExID))
+	return a.post(a.adminUrl+p.proofRecords+presExID+`/send-presentation`, data, fmt.Sprintf(`presentation sent with exchange id %s`, presExID))
 }
 
-func (a *Agent) getCredentialsFromWallet() ([]responses.WalletCredential, error) {
-	data, err := a.get(a.adminUrl+endpointCredentials, fmt.Sprintf(`fetched credentials from the wallet of %s`, a.name))
+// post proceeds with sending POST request
+func (a *Agent) post(url string, body []byte, successLog string) (response []byte, err error) {
+	res, err := a.client.Post(url, `application/json`, bytes.NewBuffer(body))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf(`transport error - %v`, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response error - %d", res.StatusCode)
 	}
 
-	var res responses.Credentials
-	err = json.Unmarshal(data, &res)
+	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf(`unmarshal error - %v`, err)
+		return nil, fmt.Errorf("reading body - %v", err)
 	}
 
-	return res.Results, nil
+	a.logger.Debug(successLog)
+	return data, nil
 }
 
-func (a *Agent) VerifyProof(presExID string) (response []byte, err error) {
-	return a.post(a.adminUrl+endpointProofRecords+presExID+`/verify-presentation`, nil, fmt.Sprintf(`verified presentation proof %s`, presExID))
-}
+// delete proceeds with sending DELETE request
+func (a *Agent) delete(url string, successLog string) (response []byte, err error) {
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http request - %v", err)
+	}
 
-// post proceeds with sending POST request
-func (a *Agent) post(url string, body []byte, successLog string) (response []byte, err error) {
-	res, err := a.client.Post(url, `application/json`, bytes.NewBuffer(body))
+	res, err := a.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf(`transport error - %v`, err)
 	}
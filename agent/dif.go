@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/requests"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/domain"
+	"net/url"
+	"strconv"
+)
+
+// difPresentation constructs the presentation of a dif request where the credentials chosen by the holder are given
+// by input descriptor, and the agent selects the credentials of the rest of the descriptors. It returns the records
+// matching each descriptor as well, among which the chosen records should be.
+func (a *Agent) difPresentation(presExID string, req domain.DIFProofRequest, pres domain.Presentation) (requests.ProofPresentation, map[string][]models.RecordCandidate, error) {
+	if len(pres.SelfAttested) > 0 || len(pres.Unrevealed) > 0 {
+		return requests.ProofPresentation{}, nil, fmt.Errorf(`self-attested and unrevealed attributes are only supported by indy presentations`)
+	}
+
+	candidates, err := a.descriptorCandidates(presExID, req)
+	if err != nil {
+		return requests.ProofPresentation{}, nil, err
+	}
+
+	dif := &requests.DIFPresentation{}
+	for id, recordID := range pres.Choices {
+		records, ok := candidates[id]
+		if !ok {
+			return requests.ProofPresentation{}, nil, fmt.Errorf(`input descriptor %s is not requested`, id)
+		}
+
+		if _, ok = findRecord(records, recordID); !ok {
+			return requests.ProofPresentation{}, nil, fmt.Errorf(`chosen record %s of input descriptor %s does not match the descriptor`, recordID, id)
+		}
+
+		if dif.RecordIDs == nil {
+			dif.RecordIDs = make(map[string][]string)
+		}
+		dif.RecordIDs[id] = []string{recordID}
+	}
+
+	return requests.ProofPresentation{Dif: dif}, candidates, nil
+}
+
+// previewDIF shows the record chosen for each input descriptor of a dif request, or the records matching the
+// descriptor for those left to the agent
+func (a *Agent) previewDIF(presExID string, req domain.DIFProofRequest, pres domain.Presentation) (models.ProofPreview, error) {
+	_, candidates, err := a.difPresentation(presExID, req, pres)
+	if err != nil {
+		return models.ProofPreview{}, err
+	}
+
+	preview := models.ProofPreview{PresExID: presExID, Attributes: []models.PreviewAttribute{}, Predicates: []models.PreviewPredicate{}}
+	for _, id := range req.DescriptorIDs() {
+		choice, ok := pres.Choices[id]
+		if !ok {
+			preview.Descriptors = append(preview.Descriptors, models.PreviewDescriptor{ID: id, Records: candidates[id]})
+			continue
+		}
+
+		rec, _ := findRecord(candidates[id], choice)
+		preview.Descriptors = append(preview.Descriptors, models.PreviewDescriptor{ID: id, Chosen: true, Records: []models.RecordCandidate{rec}})
+	}
+
+	return preview, nil
+}
+
+func findRecord(records []models.RecordCandidate, recordID string) (models.RecordCandidate, bool) {
+	for _, rec := range records {
+		if rec.RecordID == recordID {
+			return rec, true
+		}
+	}
+
+	return models.RecordCandidate{}, false
+}
+
+// descriptorCandidates returns the records matching the presentation definition by input descriptor, where the
+// records of a descriptor are those whose schemas match the schemas of the descriptor
+func (a *Agent) descriptorCandidates(presExID string, req domain.DIFProofRequest) (map[string][]models.RecordCandidate, error) {
+	records, err := a.recordCandidates(presExID)
+	if err != nil {
+		return nil, fmt.Errorf(`matching records - %v`, err)
+	}
+
+	candidates := make(map[string][]models.RecordCandidate)
+	for _, d := range req.PresentationDefinition.InputDescriptors {
+		candidates[d.ID] = []models.RecordCandidate{}
+		for _, rec := range records {
+			if d.Schema.MatchedBy(rec.SchemaIDs) {
+				candidates[d.ID] = append(candidates[d.ID], rec)
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+// recordCandidates pages through the W3C credential records which the agent matches with the presentation
+// definition of the exchange
+func (a *Agent) recordCandidates(presExID string) ([]models.RecordCandidate, error) {
+	_, p := a.exchangeProtocol(presExID)
+	records := []models.RecordCandidate{}
+	for start := 0; ; start += candidatePageSize {
+		params := url.Values{}
+		params.Add(`start`, strconv.Itoa(start))
+		params.Add(`count`, strconv.Itoa(candidatePageSize))
+
+		data, err := a.get(a.adminUrl+p.proofRecords+presExID+`/credentials?`+params.Encode(),
+			fmt.Sprintf(`fetched matching records for presentation exchange %s`, presExID))
+		if err != nil {
+			return nil, err
+		}
+
+		var page []responses.VCRecord
+		if err = json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+		}
+
+		for _, rec := range page {
+			cred, err := verifiedCredential(rec.CredValue)
+			if err != nil {
+				return nil, fmt.Errorf(`record %s - %v`, rec.RecordID, err)
+			}
+			records = append(records, models.RecordCandidate{RecordID: rec.RecordID, SchemaIDs: rec.SchemaIDs, Credential: cred})
+		}
+
+		if len(page) < candidatePageSize {
+			return records, nil
+		}
+	}
+}
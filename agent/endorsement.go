@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/responses"
+	"sort"
+)
+
+// indy ledger operation types and their transaction type names
+var ledgerTxnTypes = map[string]string{
+	`1`:   `nym`,
+	`100`: `attrib`,
+	`101`: models.TxnTypeSchema,
+	`102`: models.TxnTypeCredDef,
+	`113`: `revocation-registry-definition`,
+	`114`: `revocation-registry-entry`,
+}
+
+// SetAuthor configures this agent as the endorser for the transactions of the peer agent label
+func (a *Agent) SetAuthor(label string) error {
+	connID, err := a.GetConnectionByLabel(label)
+	if err != nil {
+		return fmt.Errorf(`get connection by label - %v`, err)
+	}
+
+	if _, err = a.post(a.adminUrl+endpointTransactions+connID+`/set-endorser-role?transaction_my_job=TRANSACTION_ENDORSER`, nil,
+		fmt.Sprintf(`endorser role set for the connection with %s`, label)); err != nil {
+		return fmt.Errorf(`set endorser role - %v`, err)
+	}
+
+	return nil
+}
+
+// EndorsementRequests returns the transactions received from authors which await a decision
+func (a *Agent) EndorsementRequests() ([]models.EndorsementRequest, error) {
+	data, err := a.get(a.adminUrl+endpointTransactions, `fetched transactions`)
+	if err != nil {
+		return nil, fmt.Errorf(`fetch transactions - %v`, err)
+	}
+
+	var records responses.TransactionRecords
+	if err = json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	list := []models.EndorsementRequest{}
+	for _, rec := range records.Results {
+		if rec.State != models.TxnStateRequestReceived {
+			continue
+		}
+
+		req, err := a.decodeEndorsementRequest(rec)
+		if err != nil {
+			a.logger.Warn(fmt.Sprintf(`skipped transaction %s - %v`, rec.TransactionID, err))
+			continue
+		}
+		list = append(list, req)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt < list[j].CreatedAt })
+	return list, nil
+}
+
+// EndorsementRequest fetches the transaction from the agent and decodes its ledger request
+func (a *Agent) EndorsementRequest(tranID string) (models.EndorsementRequest, error) {
+	data, err := a.get(a.adminUrl+endpointTransactions+tranID, fmt.Sprintf(`fetched transaction %s`, tranID))
+	if err != nil {
+		return models.EndorsementRequest{}, fmt.Errorf(`fetch transaction - %v`, err)
+	}
+
+	var rec responses.TransactionRecord
+	if err = json.Unmarshal(data, &rec); err != nil {
+		return models.EndorsementRequest{}, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	return a.decodeEndorsementRequest(rec)
+}
+
+// Endorse signs the transaction of an author and sends it back to be written to the ledger
+func (a *Agent) Endorse(tranID string) error {
+	if _, err := a.post(a.adminUrl+endpointTransactions+tranID+`/endorse`, nil, fmt.Sprintf(`transaction %s endorsed`, tranID)); err != nil {
+		return fmt.Errorf(`endorse transaction - %v`, err)
+	}
+
+	return nil
+}
+
+// Refuse declines to endorse the transaction of an author
+func (a *Agent) Refuse(tranID string) error {
+	if _, err := a.post(a.adminUrl+endpointTransactions+tranID+`/refuse`, nil, fmt.Sprintf(`transaction %s refused`, tranID)); err != nil {
+		return fmt.Errorf(`refuse transaction - %v`, err)
+	}
+
+	return nil
+}
+
+// HandleEndorsementRequest applies the endorsement policy to a transaction received from an author. Requests which
+// do not match any rule of the policy are left for manual review.
+func (a *Agent) HandleEndorsementRequest(tranID string) {
+	req, err := a.EndorsementRequest(tranID)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf(`decode endorsement request %s - %v`, tranID, err))
+		return
+	}
+
+	action, ok := a.EndorsementPolicy().Action(req.AuthorDid, req.Type)
+	if !ok {
+		a.logger.Info(fmt.Sprintf(`%s transaction %s of %s awaits review`, req.Type, tranID, req.AuthorDid))
+		return
+	}
+
+	switch action {
+	case models.EndorsementActionEndorse:
+		err = a.Endorse(tranID)
+	case models.EndorsementActionRefuse:
+		err = a.Refuse(tranID)
+	}
+
+	if err != nil {
+		a.logger.Error(fmt.Sprintf(`apply endorsement policy to transaction %s - %v`, tranID, err))
+	}
+}
+
+func (a *Agent) EndorsementPolicy() models.EndorsementPolicy {
+	a.endorsement.policyMu.Lock()
+	defer a.endorsement.policyMu.Unlock()
+	return a.endorsement.policy
+}
+
+// SetEndorsementPolicy validates and persists the policy for incoming endorsement requests
+func (a *Agent) SetEndorsementPolicy(p models.EndorsementPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	a.endorsement.policyMu.Lock()
+	defer a.endorsement.policyMu.Unlock()
+
+	if err := a.endorsement.policyStore.Save(p); err != nil {
+		return fmt.Errorf(`persist endorsement policy - %v`, err)
+	}
+
+	a.endorsement.policy = p
+	return nil
+}
+
+// decodeEndorsementRequest extracts the author and the content to be written from the ledger request attached to
+// the transaction
+func (a *Agent) decodeEndorsementRequest(rec responses.TransactionRecord) (models.EndorsementRequest, error) {
+	req := models.EndorsementRequest{
+		TransactionID: rec.TransactionID,
+		ConnectionID:  rec.ConnectionID,
+		State:         rec.State,
+		CreatedAt:     rec.CreatedAt,
+	}
+	req.AuthorLabel, _ = a.GetLabelByConnection(rec.ConnectionID)
+
+	if len(rec.MessagesAttach) == 0 {
+		return models.EndorsementRequest{}, fmt.Errorf(`no ledger request attached`)
+	}
+
+	// the ledger request is attached as a JSON encoded string by the agent
+	raw := []byte(rec.MessagesAttach[0].Data.JSON)
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		raw = []byte(str)
+	}
+
+	var ledgerReq responses.LedgerRequest
+	if err := json.Unmarshal(raw, &ledgerReq); err != nil {
+		return models.EndorsementRequest{}, fmt.Errorf(`unmarshal ledger request - %v [%s]`, err, string(raw))
+	}
+
+	var op responses.LedgerOperation
+	if err := json.Unmarshal(ledgerReq.Operation, &op); err != nil {
+		return models.EndorsementRequest{}, fmt.Errorf(`unmarshal ledger operation - %v [%s]`, err, string(ledgerReq.Operation))
+	}
+
+	req.AuthorDid = ledgerReq.Identifier
+	req.Operation = ledgerReq.Operation
+	req.Type = op.Type
+	if name, ok := ledgerTxnTypes[op.Type]; ok {
+		req.Type = name
+	}
+
+	switch req.Type {
+	case models.TxnTypeSchema:
+		var data struct {
+			Name      string   `json:"name"`
+			Version   string   `json:"version"`
+			AttrNames []string `json:"attr_names"`
+		}
+		if err := json.Unmarshal(op.Data, &data); err != nil {
+			return models.EndorsementRequest{}, fmt.Errorf(`unmarshal schema - %v [%s]`, err, string(op.Data))
+		}
+		req.Schema = &models.TxnSchema{Name: data.Name, Version: data.Version, Attributes: data.AttrNames}
+	case models.TxnTypeCredDef:
+		req.CredDef = &models.TxnCredDef{SchemaSeqNo: op.Ref, Tag: op.Tag, SignatureType: op.SignatureType}
+	}
+
+	return req, nil
+}
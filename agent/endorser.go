@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/agent/store"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// endorsement holds the endorser and the transactions of this agent as an author, and the policy as an endorser
+type endorsement struct {
+	endorser    *models.Endorser
+	mu          *sync.Mutex
+	store       *store.File
+	autoWrite   bool
+	txns        map[string]models.Transaction // transaction ID to ledger transaction map
+	txnMu       *sync.Mutex
+	txnStore    *store.File
+	timeout     time.Duration
+	policy      models.EndorsementPolicy
+	policyMu    *sync.Mutex
+	policyStore *store.File
+}
+
+func newEndorsement(cfg Config) *endorsement {
+	return &endorsement{
+		mu:          &sync.Mutex{},
+		store:       store.NewFile(cfg.DataDir, `endorser.json`),
+		autoWrite:   cfg.AutoWriteTxns,
+		txns:        make(map[string]models.Transaction),
+		txnMu:       &sync.Mutex{},
+		txnStore:    store.NewFile(cfg.DataDir, `transactions.json`),
+		timeout:     cfg.EndorsementTimeout,
+		policyMu:    &sync.Mutex{},
+		policyStore: store.NewFile(cfg.DataDir, `endorsement-policy.json`),
+	}
+}
+
+const (
+	endpointTransactions  = `/transactions/`
+	endpointTxnCreateReq  = `/transactions/create-request`
+	txnAwaitCheckInterval = 500 * time.Millisecond
+)
+
+// SetEndorser configures the connection of the peer agent label as the endorser of this agent so that schemas and
+// credential definitions are created as transactions to be endorsed before being written to the ledger
+func (a *Agent) SetEndorser(label, endorserDid, endorserName string) (models.Endorser, error) {
+	connID, err := a.GetConnectionByLabel(label)
+	if err != nil {
+		return models.Endorser{}, fmt.Errorf(`get connection by label - %v`, err)
+	}
+
+	return a.setEndorser(label, connID, endorserDid, endorserName)
+}
+
+// SetEndorserConnection is similar to SetEndorser except that the endorser is referred by the connection ID, so that
+// the endorser can be configured at startup before the labels of the connections are known
+func (a *Agent) SetEndorserConnection(connID, endorserDid, endorserName string) (models.Endorser, error) {
+	label, _ := a.GetLabelByConnection(connID)
+	return a.setEndorser(label, connID, endorserDid, endorserName)
+}
+
+func (a *Agent) setEndorser(label, connID, endorserDid, endorserName string) (models.Endorser, error) {
+	if _, err := a.post(a.adminUrl+endpointTransactions+connID+`/set-endorser-role?transaction_my_job=TRANSACTION_AUTHOR`, nil,
+		fmt.Sprintf(`author role set for the connection %s`, connID)); err != nil {
+		return models.Endorser{}, fmt.Errorf(`set endorser role - %v`, err)
+	}
+
+	params := url.Values{}
+	params.Add(`endorser_did`, endorserDid)
+	if endorserName != `` {
+		params.Add(`endorser_name`, endorserName)
+	}
+
+	if _, err := a.post(a.adminUrl+endpointTransactions+connID+`/set-endorser-info?`+params.Encode(), nil,
+		fmt.Sprintf(`endorser info set for the connection %s`, connID)); err != nil {
+		return models.Endorser{}, fmt.Errorf(`set endorser info - %v`, err)
+	}
+
+	e := models.Endorser{Label: label, ConnectionID: connID, Did: endorserDid, Name: endorserName}
+	a.endorsement.mu.Lock()
+	defer a.endorsement.mu.Unlock()
+
+	if err := a.endorsement.store.Save(e); err != nil {
+		return models.Endorser{}, fmt.Errorf(`persist endorser - %v`, err)
+	}
+	a.endorsement.endorser = &e
+
+	a.logger.Info(fmt.Sprintf(`connection %s [%s] is set as the endorser of this agent`, connID, label))
+	return e, nil
+}
+
+// Endorser returns the endorser of this agent if configured
+func (a *Agent) Endorser() (models.Endorser, bool) {
+	a.endorsement.mu.Lock()
+	defer a.endorsement.mu.Unlock()
+
+	if a.endorsement.endorser == nil {
+		return models.Endorser{}, false
+	}
+
+	return *a.endorsement.endorser, true
+}
+
+// postLedgerWrite sends the schema or credential definition to the agent, or as a transaction to the endorser if
+// configured in which case it waits for the write unless no endorsement timeout is configured
+func (a *Agent) postLedgerWrite(endpoint string, body []byte, txnType, successLog string) (response []byte, err error) {
+	e, ok := a.Endorser()
+	if !ok {
+		return a.post(a.adminUrl+endpoint, body, successLog)
+	}
+
+	params := url.Values{}
+	params.Add(`conn_id`, e.ConnectionID)
+	params.Add(`create_transaction_for_endorser`, `true`)
+
+	res, err := a.post(a.adminUrl+endpoint+`?`+params.Encode(), body, successLog+` as a transaction for the endorser`)
+	if err != nil {
+		return nil, err
+	}
+
+	var endorsed struct {
+		Sent json.RawMessage       `json:"sent"`
+		Txn  responses.Transaction `json:"txn"`
+	}
+	if err = json.Unmarshal(res, &endorsed); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(res))
+	}
+
+	var ids struct {
+		SchemaID  string `json:"schema_id"`
+		CredDefID string `json:"credential_definition_id"`
+	}
+	if err = json.Unmarshal(endorsed.Sent, &ids); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(endorsed.Sent))
+	}
+
+	now := time.Now().UTC()
+	txn := models.Transaction{
+		TransactionID: endorsed.Txn.TransactionID,
+		ConnectionID:  e.ConnectionID,
+		Type:          txnType,
+		LedgerID:      ids.SchemaID + ids.CredDefID,
+		State:         endorsed.Txn.State,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err = a.saveTransaction(txn); err != nil {
+		return nil, err
+	}
+
+	// agents configured to request endorsements automatically would have already sent the request
+	if txn.State == models.TxnStateCreated {
+		if _, err = a.post(a.adminUrl+endpointTxnCreateReq+`?tran_id=`+url.QueryEscape(txn.TransactionID), []byte(`{}`),
+			fmt.Sprintf(`endorsement requested for transaction %s`, txn.TransactionID)); err != nil {
+			return nil, fmt.Errorf(`request endorsement - %v`, err)
+		}
+	}
+
+	if a.endorsement.timeout > 0 {
+		if err = a.awaitTransaction(txn.TransactionID, a.endorsement.timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	return endorsed.Sent, nil
+}
+
+// awaitTransaction blocks until the transaction is written to the ledger, refused or the timeout is reached
+func (a *Agent) awaitTransaction(tranID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		txn, err := a.Transaction(tranID)
+		if err != nil {
+			return err
+		}
+
+		switch txn.State {
+		case models.TxnStateAcked:
+			return nil
+		case models.TxnStateRefused:
+			return fmt.Errorf(`transaction %s was refused by the endorser`, tranID)
+		}
+
+		time.Sleep(txnAwaitCheckInterval)
+	}
+
+	return fmt.Errorf(`transaction %s was not written within %s and can be tracked via the controller`, tranID, timeout)
+}
+
+// UpdateTransaction updates the state of a transaction of this agent received by the webhook, and writes it to the
+// ledger once endorsed unless ACA-Py writes it automatically
+func (a *Agent) UpdateTransaction(tranID, state string) {
+	txn, err := a.Transaction(tranID)
+	if err != nil {
+		return
+	}
+
+	txn.State = state
+	txn.UpdatedAt = time.Now().UTC()
+	if err = a.saveTransaction(txn); err != nil {
+		a.logger.Error(fmt.Sprintf(`update transaction - %v`, err))
+	}
+	a.logger.Debug("transaction updated", tranID, state)
+
+	if state != models.TxnStateEndorsed || a.endorsement.autoWrite {
+		return
+	}
+
+	if _, err = a.post(a.adminUrl+endpointTransactions+tranID+`/write`, nil, fmt.Sprintf(`endorsed transaction %s written to the ledger`, tranID)); err != nil {
+		a.logger.Error(fmt.Sprintf(`write endorsed transaction %s - %v`, tranID, err))
+	}
+}
+
+func (a *Agent) Transaction(tranID string) (models.Transaction, error) {
+	a.endorsement.txnMu.Lock()
+	defer a.endorsement.txnMu.Unlock()
+
+	txn, ok := a.endorsement.txns[tranID]
+	if !ok {
+		return models.Transaction{}, fmt.Errorf(`no transaction found for id %s`, tranID)
+	}
+
+	return txn, nil
+}
+
+// Transactions returns the transactions created by this agent ordered by creation
+func (a *Agent) Transactions() []models.Transaction {
+	a.endorsement.txnMu.Lock()
+	var list []models.Transaction
+	for _, txn := range a.endorsement.txns {
+		list = append(list, txn)
+	}
+	a.endorsement.txnMu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// saveTransaction persists the transaction so that it is still written once endorsed after a restart
+func (a *Agent) saveTransaction(txn models.Transaction) error {
+	a.endorsement.txnMu.Lock()
+	defer a.endorsement.txnMu.Unlock()
+
+	a.endorsement.txns[txn.TransactionID] = txn
+	if err := a.endorsement.txnStore.Save(a.endorsement.txns); err != nil {
+		return fmt.Errorf(`persist transaction %s - %v`, txn.TransactionID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// event topics
+const (
+	TopicConnection = `connection`
+	TopicCredential = `credential`
+	TopicProof      = `proof`
+	TopicMessage    = `message`
+)
+
+const (
+	historySize      = 1000
+	subscriberBuffer = 64
+)
+
+// Event is the normalized form of a webhook received from the agent which is streamed to controller clients
+type Event struct {
+	ID           int64     `json:"id"`
+	Topic        string    `json:"topic"`
+	ConnectionID string    `json:"connection_id,omitempty"`
+	Label        string    `json:"label,omitempty"`
+	ExchangeID   string    `json:"exchange_id,omitempty"`
+	Role         string    `json:"role,omitempty"`
+	State        string    `json:"state,omitempty"`
+	Content      string    `json:"content,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Filter selects events of a subscription where empty fields match any value
+type Filter struct {
+	Topics       map[string]bool
+	ConnectionID string
+}
+
+func (f Filter) match(e Event) bool {
+	if len(f.Topics) > 0 && !f.Topics[e.Topic] {
+		return false
+	}
+
+	return f.ConnectionID == `` || f.ConnectionID == e.ConnectionID
+}
+
+// Broker fans out published events to subscribers and keeps the latest events in memory so that clients can resume
+// a stream from the last event they received
+type Broker struct {
+	mu      *sync.Mutex
+	lastID  int64
+	history []Event
+	subs    map[*Subscription]bool
+}
+
+type Subscription struct {
+	filter Filter
+	ch     chan Event
+	closed bool
+}
+
+// Events returns the channel of the subscription which is closed either when the subscription is cancelled or when
+// the subscriber falls too far behind, in which case it should resume with the ID of the last received event
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+func NewBroker() *Broker {
+	return &Broker{mu: &sync.Mutex{}, subs: make(map[*Subscription]bool)}
+}
+
+// Publish assigns the next ID and the current time to the event and delivers it to all matching subscribers
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e.ID = b.lastID
+	e.Time = time.Now().UTC()
+
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.match(e) {
+			continue
+		}
+
+		select {
+		case sub.ch <- e:
+		default:
+			b.cancel(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscription with the filter and returns the retained events after lastID which match the
+// filter. A zero lastID does not replay any event.
+func (b *Broker) Subscribe(filter Filter, lastID int64) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+	if lastID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastID && filter.match(e) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	sub := &Subscription{filter: filter, ch: make(chan Event, subscriberBuffer)}
+	b.subs[sub] = true
+	return sub, backlog
+}
+
+// Unsubscribe cancels the subscription and closes its channel
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cancel(sub)
+}
+
+func (b *Broker) cancel(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+
+	sub.closed = true
+	delete(b.subs, sub)
+	close(sub.ch)
+}
@@ -0,0 +1,96 @@
+package events
+
+import "testing"
+
+func TestBrokerSubscribeReplay(t *testing.T) {
+	topics := []string{TopicConnection, TopicCredential, TopicProof, TopicCredential, TopicCredential}
+	tests := []struct {
+		name    string
+		filter  Filter
+		lastID  int64
+		wantIDs []int64
+	}{
+		{name: `no last event`, filter: Filter{}, lastID: 0, wantIDs: nil},
+		{name: `all topics`, filter: Filter{}, lastID: 2, wantIDs: []int64{3, 4, 5}},
+		{name: `filtered topic`, filter: Filter{Topics: map[string]bool{TopicCredential: true}}, lastID: 1, wantIDs: []int64{2, 4, 5}},
+		{name: `filtered connection`, filter: Filter{ConnectionID: `conn-2`}, lastID: 1, wantIDs: []int64{4}},
+		{name: `latest event`, filter: Filter{}, lastID: 5, wantIDs: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker()
+			for i, topic := range topics {
+				connID := `conn-1`
+				if i == 3 {
+					connID = `conn-2`
+				}
+				b.Publish(Event{Topic: topic, ConnectionID: connID})
+			}
+
+			sub, backlog := b.Subscribe(tc.filter, tc.lastID)
+			defer b.Unsubscribe(sub)
+
+			var ids []int64
+			for _, e := range backlog {
+				ids = append(ids, e.ID)
+			}
+
+			if len(ids) != len(tc.wantIDs) {
+				t.Fatalf(`got events %v, want %v`, ids, tc.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tc.wantIDs[i] {
+					t.Fatalf(`got events %v, want %v`, ids, tc.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerHistoryLimit(t *testing.T) {
+	b := NewBroker()
+	for i := 0; i < historySize+10; i++ {
+		b.Publish(Event{Topic: TopicMessage})
+	}
+
+	sub, backlog := b.Subscribe(Filter{}, 1)
+	defer b.Unsubscribe(sub)
+
+	if len(backlog) != historySize {
+		t.Fatalf(`got %d retained events, want %d`, len(backlog), historySize)
+	}
+
+	if backlog[0].ID != 11 {
+		t.Errorf(`got %d as the oldest retained event, want 11`, backlog[0].ID)
+	}
+}
+
+func TestBrokerEvictSlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	slow, _ := b.Subscribe(Filter{}, 0)
+	other, _ := b.Subscribe(Filter{Topics: map[string]bool{TopicProof: true}}, 0)
+	defer b.Unsubscribe(other)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		b.Publish(Event{Topic: TopicMessage})
+	}
+
+	var received int
+	for range slow.Events() {
+		received++
+	}
+
+	if received != subscriberBuffer {
+		t.Errorf(`got %d events before eviction, want %d`, received, subscriberBuffer)
+	}
+
+	// subscribers which are not delivered the events should not be evicted
+	b.Publish(Event{Topic: TopicProof})
+	if e, ok := <-other.Events(); !ok || e.Topic != TopicProof {
+		t.Errorf(`got %+v [open: %t], want the proof event`, e, ok)
+	}
+
+	// cancelling an evicted subscription should not close its channel again
+	b.Unsubscribe(slow)
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/store"
+	"sort"
+	"sync"
+	"time"
+)
+
+// expiry holds the exchanges which should be answered by the peer within the TTL of their type
+type expiry struct {
+	credTTL  time.Duration
+	proofTTL time.Duration
+	pending  map[string]models.PendingExchange // exchange ID to pending exchange map
+	mu       *sync.Mutex
+	store    *store.File
+}
+
+func newExpiry(cfg Config) *expiry {
+	return &expiry{
+		credTTL:  cfg.CredentialTTL,
+		proofTTL: cfg.ProofTTL,
+		pending:  make(map[string]models.PendingExchange),
+		mu:       &sync.Mutex{},
+		store:    store.NewFile(cfg.DataDir, `pending-exchanges.json`),
+	}
+}
+
+const expiryCheckInterval = time.Minute
+
+// states in which an issuer or a verifier waits for the peer to respond to the exchange
+var awaitingStates = map[string]string{
+	models.ExchangeTypeCredential: `offer-sent`,
+	models.ExchangeTypeProof:      `request-sent`,
+}
+
+// TrackExchange registers an exchange sent by the issuer or the verifier which the peer is expected to answer
+// within the configured TTL of its type. Exchanges of types without a TTL are not tracked.
+func (a *Agent) TrackExchange(exType, exID, role, label string) {
+	ttl := a.ttl(exType)
+	if ttl <= 0 || exID == `` {
+		return
+	}
+
+	a.expiry.mu.Lock()
+	defer a.expiry.mu.Unlock()
+
+	if _, ok := a.expiry.pending[exID]; ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	ex := models.PendingExchange{ExchangeID: exID, Type: exType, Role: role, Label: label, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	a.expiry.pending[exID] = ex
+	if err := a.expiry.store.Save(a.expiry.pending); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist pending exchange %s - %v`, exID, err))
+	}
+}
+
+// UpdateExchangeState resolves the exchange once the peer has responded to it, i.e. it has left the awaiting state
+func (a *Agent) UpdateExchangeState(exType, exID, state string) {
+	if awaiting, ok := awaitingStates[exType]; ok && state != awaiting {
+		a.ResolveExchange(exID)
+	}
+}
+
+// ResolveExchange stops tracking the exchange since the peer has responded to it or it has been removed
+func (a *Agent) ResolveExchange(exID string) {
+	a.expiry.mu.Lock()
+	defer a.expiry.mu.Unlock()
+
+	if _, ok := a.expiry.pending[exID]; !ok {
+		return
+	}
+
+	delete(a.expiry.pending, exID)
+	if err := a.expiry.store.Save(a.expiry.pending); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist resolved exchange %s - %v`, exID, err))
+	}
+}
+
+// PendingExchanges returns the tracked exchanges of the given type ordered by their expiry
+func (a *Agent) PendingExchanges(exType string) []models.PendingExchange {
+	a.expiry.mu.Lock()
+	var list []models.PendingExchange
+	for _, ex := range a.expiry.pending {
+		if ex.Type == exType {
+			list = append(list, ex)
+		}
+	}
+	a.expiry.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ExpiresAt.Before(list[j].ExpiresAt) })
+	return list
+}
+
+func (a *Agent) PendingExchange(exID string) (models.PendingExchange, error) {
+	a.expiry.mu.Lock()
+	defer a.expiry.mu.Unlock()
+
+	ex, ok := a.expiry.pending[exID]
+	if !ok {
+		return models.PendingExchange{}, fmt.Errorf(`no pending exchange found for id %s`, exID)
+	}
+
+	return ex, nil
+}
+
+// withExpiry adds the expiry of the exchange to the given record returned by ACA-Py if the exchange is still pending
+func (a *Agent) withExpiry(exID string, record []byte) ([]byte, error) {
+	ex, err := a.PendingExchange(exID)
+	if err != nil {
+		return record, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(record, &fields); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v`, err)
+	}
+
+	if fields[`expires_at`], err = json.Marshal(ex.ExpiresAt); err != nil {
+		return nil, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	return json.Marshal(fields)
+}
+
+// RunExpiryScheduler periodically abandons the tracked exchanges which have passed their expiry by sending a problem
+// report to the peer. It blocks and should be started as a goroutine.
+func (a *Agent) RunExpiryScheduler() {
+	if a.expiry.credTTL <= 0 && a.expiry.proofTTL <= 0 {
+		a.logger.Info(`exchange expiry scheduler is disabled since no TTL is configured`)
+		return
+	}
+
+	a.logger.Info(fmt.Sprintf(`exchange expiry scheduler started with credential TTL %s and proof TTL %s`, a.expiry.credTTL, a.expiry.proofTTL))
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		var expired []models.PendingExchange
+		a.expiry.mu.Lock()
+		for _, ex := range a.expiry.pending {
+			if now.After(ex.ExpiresAt) {
+				expired = append(expired, ex)
+			}
+		}
+		a.expiry.mu.Unlock()
+
+		for _, ex := range expired {
+			if err := a.abandonExchange(ex); err != nil {
+				a.logger.Error(fmt.Sprintf(`abandon expired exchange %s - %v`, ex.ExchangeID, err))
+			}
+		}
+	}
+}
+
+func (a *Agent) abandonExchange(ex models.PendingExchange) error {
+	data, err := json.Marshal(map[string]string{`description`: fmt.Sprintf(`exchange expired at %s`, ex.ExpiresAt.Format(time.RFC3339))})
+	if err != nil {
+		return fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	_, p := a.exchangeProtocol(ex.ExchangeID)
+	switch ex.Type {
+	case models.ExchangeTypeCredential:
+		_, err = a.post(a.adminUrl+p.credRecords+ex.ExchangeID+`/problem-report`, data, fmt.Sprintf(`abandoned expired credential exchange %s`, ex.ExchangeID))
+		if err == nil {
+			a.RemoveCredentialRecord(ex.ExchangeID)
+		}
+	case models.ExchangeTypeProof:
+		_, err = a.post(a.adminUrl+p.proofRecords+ex.ExchangeID+`/problem-report`, data, fmt.Sprintf(`abandoned expired presentation exchange %s`, ex.ExchangeID))
+		if err == nil {
+			a.RemovePresentationRecord(ex.ExchangeID)
+		}
+	default:
+		err = fmt.Errorf(`unknown exchange type %s`, ex.Type)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	a.ResolveExchange(ex.ExchangeID)
+	return nil
+}
+
+func (a *Agent) ttl(exType string) time.Duration {
+	switch exType {
+	case models.ExchangeTypeCredential:
+		return a.expiry.credTTL
+	case models.ExchangeTypeProof:
+		return a.expiry.proofTTL
+	}
+
+	return 0
+}
@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/store"
+	"github.com/YasiruR/agent/domain"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lineage holds the schema families of the schemas created by this agent by name
+type lineage struct {
+	families map[string]models.SchemaFamily
+	mu       *sync.Mutex
+	store    *store.File
+}
+
+func newLineage(dataDir string) *lineage {
+	return &lineage{families: make(map[string]models.SchemaFamily), mu: &sync.Mutex{}, store: store.NewFile(dataDir, `schema-lineage.json`)}
+}
+
+// EvolveSchema publishes the next version of the latest schema of a family created by this agent along with a
+// credential definition, and records it in the lineage
+func (a *Agent) EvolveSchema(e domain.SchemaEvolution) (models.SchemaVersion, error) {
+	if e.Tag == `` {
+		e.Tag = defaultCredDefTag
+	}
+
+	source, err := a.Schema(e.SchemaID)
+	if err != nil {
+		return models.SchemaVersion{}, fmt.Errorf(`fetch schema - %v`, err)
+	}
+
+	did, err := a.publicDid()
+	if err != nil {
+		return models.SchemaVersion{}, fmt.Errorf(`fetch public DID - %v`, err)
+	}
+
+	if !strings.HasPrefix(source.ID, did+`:`) {
+		return models.SchemaVersion{}, fmt.Errorf(`schema %s is not created by this agent`, source.ID)
+	}
+
+	if family, err := a.SchemaFamily(source.Name); err == nil {
+		latest, _ := family.Latest()
+		if latest.Meta.SchemaID != source.ID {
+			return models.SchemaVersion{}, fmt.Errorf(`schema %s is not the latest version of family %s (%s)`, source.ID, source.Name, latest.Meta.SchemaID)
+		}
+	} else {
+		// schemas created before the lineage was recorded become the root of the family
+		credDefID, err := a.createdCredentialDef(source.ID, did, e.Tag)
+		if err != nil {
+			return models.SchemaVersion{}, fmt.Errorf(`fetch created credential definitions - %v`, err)
+		}
+
+		root := domain.IndySchemaMeta{CredDefID: credDefID, IssuerDid: did, SchemaID: source.ID, SchemaIssuerDid: did, SchemaName: source.Name, SchemaVersion: source.Version}
+		if err = a.recordSchemaVersion(models.SchemaVersion{Meta: root, Attributes: source.AttrNames}); err != nil {
+			return models.SchemaVersion{}, err
+		}
+	}
+
+	next, err := source.Evolve(e)
+	if err != nil {
+		return models.SchemaVersion{}, fmt.Errorf(`evolve schema - %v`, err)
+	}
+
+	res, err := a.ensureCredentialDef(domain.CredentialDefinitionSetup{
+		Schema:                 &next,
+		Tag:                    e.Tag,
+		SupportRevocation:      e.SupportRevocation,
+		RevocationRegistrySize: e.RevocationRegistrySize,
+	}, source.ID)
+	if err != nil {
+		return models.SchemaVersion{}, err
+	}
+
+	v, err := a.schemaVersion(source.Name, res.Meta.SchemaID)
+	if err != nil {
+		return models.SchemaVersion{}, err
+	}
+
+	a.logger.Info(fmt.Sprintf(`schema %s evolved to version %s [%s]`, source.Name, next.SchemaVersion, res.Meta.SchemaID))
+	return v, nil
+}
+
+// SchemaFamily returns the lineage of the schema family with versions in ascending order
+func (a *Agent) SchemaFamily(name string) (models.SchemaFamily, error) {
+	a.lineage.mu.Lock()
+	defer a.lineage.mu.Unlock()
+
+	family, ok := a.lineage.families[name]
+	if !ok {
+		return models.SchemaFamily{}, fmt.Errorf(`no schema family found for name %s`, name)
+	}
+
+	return family, nil
+}
+
+// schemaVersion returns the version of the family recorded for the schema ID
+func (a *Agent) schemaVersion(name, schemaID string) (models.SchemaVersion, error) {
+	family, err := a.SchemaFamily(name)
+	if err != nil {
+		return models.SchemaVersion{}, err
+	}
+
+	for _, v := range family.Versions {
+		if v.Meta.SchemaID == schemaID {
+			return v, nil
+		}
+	}
+
+	return models.SchemaVersion{}, fmt.Errorf(`schema %s is not recorded in family %s`, schemaID, name)
+}
+
+// SchemaFamilies returns the lineages of all schema families sorted by name
+func (a *Agent) SchemaFamilies() []models.SchemaFamily {
+	a.lineage.mu.Lock()
+	defer a.lineage.mu.Unlock()
+
+	list := []models.SchemaFamily{}
+	for _, family := range a.lineage.families {
+		list = append(list, family)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// recordSchemaVersion adds the version to the lineage of its family, or updates the version if it has already been
+// recorded, and persists the lineage
+func (a *Agent) recordSchemaVersion(v models.SchemaVersion) error {
+	a.lineage.mu.Lock()
+	defer a.lineage.mu.Unlock()
+
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now().UTC()
+	}
+
+	family := a.lineage.families[v.Meta.SchemaName]
+	family.Name = v.Meta.SchemaName
+	versions := make([]models.SchemaVersion, 0, len(family.Versions)+1)
+	for _, existing := range family.Versions {
+		if existing.Meta.SchemaID == v.Meta.SchemaID {
+			if v.PreviousSchemaID == `` {
+				v.PreviousSchemaID = existing.PreviousSchemaID
+			}
+			v.CreatedAt = existing.CreatedAt
+			continue
+		}
+		versions = append(versions, existing)
+	}
+	versions = append(versions, v)
+	sort.Slice(versions, func(i, j int) bool {
+		return domain.CompareSchemaVersions(versions[i].Meta.SchemaVersion, versions[j].Meta.SchemaVersion) < 0
+	})
+
+	old, existed := a.lineage.families[family.Name]
+	family.Versions = versions
+	a.lineage.families[family.Name] = family
+	if err := a.lineage.store.Save(a.lineage.families); err != nil {
+		if existed {
+			a.lineage.families[family.Name] = old
+		} else {
+			delete(a.lineage.families, family.Name)
+		}
+		return fmt.Errorf(`persist schema lineage - %v`, err)
+	}
+
+	return nil
+}
+
+// latestOfFamily returns the latest version of the family which has a credential definition to issue with
+func (a *Agent) latestOfFamily(name string) (models.SchemaVersion, error) {
+	family, err := a.SchemaFamily(name)
+	if err != nil {
+		return models.SchemaVersion{}, err
+	}
+
+	latest, ok := family.Latest()
+	if !ok || latest.Meta.CredDefID == `` {
+		return models.SchemaVersion{}, fmt.Errorf(`latest version of schema family %s has no credential definition`, name)
+	}
+
+	return latest, nil
+}
+
+// resolveSchemaFamilies replaces the schema families of requested attributes and predicates with restrictions on
+// the schema name and issuer, which is this agent unless given
+func (a *Agent) resolveSchemaFamilies(pr *domain.IndyProofRequest) error {
+	var did string
+	restriction := func(name, issuer string) (domain.Restriction, error) {
+		if issuer != `` {
+			return domain.Restriction{SchemaName: name, SchemaIssuerDid: issuer}, nil
+		}
+
+		if did == `` {
+			var err error
+			if did, err = a.publicDid(); err != nil {
+				return domain.Restriction{}, fmt.Errorf(`fetch public DID - %v`, err)
+			}
+		}
+
+		return domain.Restriction{SchemaName: name, SchemaIssuerDid: did}, nil
+	}
+
+	for ref, attr := range pr.RequestedAttributes {
+		if attr.SchemaFamily == `` {
+			continue
+		}
+
+		r, err := restriction(attr.SchemaFamily, attr.SchemaFamilyIssuer)
+		if err != nil {
+			return fmt.Errorf(`requested attribute %s - %v`, ref, err)
+		}
+
+		attr.Restrictions, attr.SchemaFamily, attr.SchemaFamilyIssuer = append(attr.Restrictions, r), ``, ``
+		pr.RequestedAttributes[ref] = attr
+	}
+
+	for ref, pred := range pr.RequestedPredicates {
+		if pred.SchemaFamily == `` {
+			continue
+		}
+
+		r, err := restriction(pred.SchemaFamily, pred.SchemaFamilyIssuer)
+		if err != nil {
+			return fmt.Errorf(`requested predicate %s - %v`, ref, err)
+		}
+
+		pred.Restrictions, pred.SchemaFamily, pred.SchemaFamilyIssuer = append(pred.Restrictions, r), ``, ``
+		pr.RequestedPredicates[ref] = pred
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"github.com/YasiruR/agent/domain"
+	"time"
+)
+
+// audit outcomes of issued credentials
+const (
+	AuditOutcomeIssued    = `issued`
+	AuditOutcomeStored    = `stored`
+	AuditOutcomeRevoked   = `revoked`
+	AuditOutcomeAbandoned = `abandoned`
+)
+
+// IssuanceEvent carries the details of a credential exchange state change received by the webhook which are
+// relevant for auditing
+type IssuanceEvent struct {
+	CredExID     string
+	ConnectionID string
+	CredDefID    string
+	State        string
+	Attributes   []domain.CredentialAttribute
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+// AuditEntry is a record of the issuance audit ledger where attribute values are stored as HMAC-SHA256 digests
+// keyed with the audit key, and Hash chains the entry to the previous one
+type AuditEntry struct {
+	Seq          int64            `json:"seq"`
+	CredExID     string           `json:"cred_ex_id"`
+	ConnectionID string           `json:"connection_id"`
+	HolderLabel  string           `json:"holder_label"`
+	CredDefID    string           `json:"cred_def_id"`
+	Attributes   []AuditAttribute `json:"attributes"`
+	Outcome      string           `json:"outcome"`
+	State        string           `json:"state"`
+	CreatedAt    string           `json:"exchange_created_at"`
+	UpdatedAt    string           `json:"exchange_updated_at"`
+	RecordedAt   time.Time        `json:"recorded_at"`
+	PrevHash     string           `json:"prev_hash"`
+	Hash         string           `json:"hash"`
+}
+
+type AuditAttribute struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+// AuditVerification is the outcome of verifying the hash chain of the audit ledger, where BrokenAt is the sequence
+// of the first entry which was modified or does not follow the previous one
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	LastHash string `json:"last_hash"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// AuditFilter selects audit entries where empty fields match any value
+type AuditFilter struct {
+	CredExID    string
+	HolderLabel string
+	CredDefID   string
+	Outcome     string
+	From        time.Time
+	To          time.Time
+}
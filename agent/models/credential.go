@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// OfferOptions overrides the global exchange record settings for a single credential offer where nil fields fall
+// back to the global configuration
+type OfferOptions struct {
+	AutoRemove *bool
+	Retention  *time.Duration
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// exchange types tracked for expiry
+const (
+	ExchangeTypeCredential = `credential`
+	ExchangeTypeProof      = `proof`
+)
+
+// PendingExchange is a credential or proof exchange which has not reached a terminal state yet. If it does not do
+// so before ExpiresAt, the exchange is abandoned by the controller.
+type PendingExchange struct {
+	ExchangeID string    `json:"exchange_id"`
+	Type       string    `json:"type"`
+	Role       string    `json:"role"`
+	Label      string    `json:"label"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ConnectionlessExchange is an exchange whose initial message is embedded in an out-of-band invitation and which is
+// correlated with the subsequent webhooks by the thread ID of that message
+type ConnectionlessExchange struct {
+	Type          string          `json:"type"`
+	ExchangeID    string          `json:"exchange_id"`
+	ThreadID      string          `json:"thread_id"`
+	ConnectionID  string          `json:"connection_id,omitempty"`
+	State         string          `json:"state"`
+	InvitationURL string          `json:"invitation_url"`
+	Invitation    json.RawMessage `json:"invitation"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
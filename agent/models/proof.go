@@ -1,9 +1,168 @@
 package models
 
-import "github.com/YasiruR/agent/domain"
+import (
+	"github.com/YasiruR/agent/domain"
+	"time"
+)
 
 // ProofPresentation is used as the value type for storing presentation requests in memory map
 type ProofPresentation struct {
 	PresExID string
 	PresReq  domain.PresentationRequest
 }
+
+// ProofRequestOptions configures a single proof request where the presentation is verified as soon as it is
+// received if auto verification is enabled, and the outcome is then posted to the callback URL if one is given
+type ProofRequestOptions struct {
+	AutoVerify  bool
+	CallbackURL string
+}
+
+// VerificationRecord is the stored outcome of verifying the presentation of an exchange, which holds the error
+// instead of the result if the presentation could not be verified
+type VerificationRecord struct {
+	PresExID       string              `json:"pres_ex_id"`
+	AutoVerify     bool                `json:"auto_verify"`
+	CallbackURL    string              `json:"callback_url,omitempty"`
+	Result         *VerificationResult `json:"result,omitempty"`
+	Error          string              `json:"error,omitempty"`
+	VerifiedAt     *time.Time          `json:"verified_at,omitempty"`
+	Notified       bool                `json:"notified"`
+	NotifyAttempts int                 `json:"notify_attempts,omitempty"`
+	ExpiresAt      *time.Time          `json:"expires_at,omitempty"`
+}
+
+// VerificationResult is the outcome of verifying a presentation with the values disclosed for each referent and the
+// credentials they were proved by, along with the attributes which were self-attested by the holder instead
+type VerificationResult struct {
+	PresExID     string                  `json:"pres_ex_id"`
+	State        string                  `json:"state"`
+	Format       string                  `json:"format"`
+	Verified     bool                    `json:"verified"`
+	VerifiedMsgs []string                `json:"verified_msgs,omitempty"`
+	Attributes   []VerifiedAttribute     `json:"attributes"`
+	Predicates   []VerifiedPredicate     `json:"predicates"`
+	SelfAttested []SelfAttestedAttribute `json:"self_attested_attributes"`
+	Descriptors  []VerifiedDescriptor    `json:"descriptors,omitempty"`
+}
+
+// VerifiedAttribute is a requested attribute or group proved by a credential, where values are empty if the holder
+// did not reveal them
+type VerifiedAttribute struct {
+	Referent string            `json:"referent"`
+	Revealed bool              `json:"revealed"`
+	Values   map[string]string `json:"values"`
+	Source   CredentialSource  `json:"source"`
+}
+
+// VerifiedPredicate is a requested predicate and whether it was proved to hold
+type VerifiedPredicate struct {
+	Referent  string           `json:"referent"`
+	Name      string           `json:"name"`
+	PType     string           `json:"p_type"`
+	PValue    int64            `json:"p_value"`
+	Satisfied bool             `json:"satisfied"`
+	Source    CredentialSource `json:"source"`
+}
+
+type SelfAttestedAttribute struct {
+	Referent string `json:"referent"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+}
+
+// VerifiedDescriptor is an input descriptor of a dif presentation along with the credential submitted for it
+type VerifiedDescriptor struct {
+	ID         string             `json:"id"`
+	Format     string             `json:"format"`
+	Credential VerifiedCredential `json:"credential"`
+}
+
+// VerifiedCredential holds the claims of a W3C credential disclosed in a dif presentation
+type VerifiedCredential struct {
+	ID           string                   `json:"id,omitempty"`
+	Types        []string                 `json:"types"`
+	Issuer       string                   `json:"issuer"`
+	IssuanceDate string                   `json:"issuance_date,omitempty"`
+	Subjects     []map[string]interface{} `json:"subjects"`
+}
+
+// CredentialSource identifies the credential used for a referent without disclosing the credential itself
+type CredentialSource struct {
+	CredDefID string `json:"cred_def_id"`
+	SchemaID  string `json:"schema_id"`
+	IssuerDid string `json:"issuer_did"`
+	RevRegID  string `json:"rev_reg_id,omitempty"`
+}
+
+// Candidates are the credentials which can be used for each referent in the order of the selection policy, or the
+// records matching each input descriptor of a dif request
+type Candidates struct {
+	PresExID    string                       `json:"pres_ex_id"`
+	Attributes  map[string][]Candidate       `json:"attributes"`
+	Predicates  map[string][]Candidate       `json:"predicates"`
+	Descriptors map[string][]RecordCandidate `json:"descriptors,omitempty"` // input descriptor ID to matching records map
+}
+
+type Candidate struct {
+	CredID    string            `json:"cred_id"`
+	CredDefID string            `json:"cred_def_id"`
+	SchemaID  string            `json:"schema_id"`
+	RevRegID  string            `json:"rev_reg_id,omitempty"`
+	Attrs     map[string]string `json:"attrs"`
+	StoredAt  *time.Time        `json:"stored_at,omitempty"`
+}
+
+// RecordCandidate is a W3C credential record of the wallet matching a presentation definition
+type RecordCandidate struct {
+	RecordID   string             `json:"record_id"`
+	SchemaIDs  []string           `json:"schema_ids"`
+	Credential VerifiedCredential `json:"credential"`
+}
+
+// ProofPreview shows what a verifier learns from a presentation before it is sent, where dif presentations list
+// the records of each input descriptor instead
+type ProofPreview struct {
+	PresExID    string              `json:"pres_ex_id"`
+	Verifier    string              `json:"verifier"`
+	Attributes  []PreviewAttribute  `json:"attributes"`
+	Predicates  []PreviewPredicate  `json:"predicates"`
+	Descriptors []PreviewDescriptor `json:"descriptors,omitempty"`
+}
+
+type PreviewAttribute struct {
+	Referent       string            `json:"referent"`
+	Names          []string          `json:"names"`
+	CredID         string            `json:"cred_id,omitempty"`
+	CredDefID      string            `json:"cred_def_id,omitempty"`
+	SchemaID       string            `json:"schema_id,omitempty"`
+	Revealed       bool              `json:"revealed"`
+	SelfAttested   bool              `json:"self_attested"`
+	Values         map[string]string `json:"values"`
+	VerifierLearns string            `json:"verifier_learns"`
+}
+
+// what the verifier learns of a previewed attribute, where a proven attribute discloses that a credential meeting
+// the restrictions holds it without disclosing its value
+const (
+	DisclosureValue  = `value`
+	DisclosureProven = `proven`
+)
+
+// PreviewDescriptor holds the record chosen by the holder for an input descriptor, or otherwise all records matching
+// the descriptor among which the agent selects the credential
+type PreviewDescriptor struct {
+	ID      string            `json:"id"`
+	Chosen  bool              `json:"chosen"`
+	Records []RecordCandidate `json:"records"`
+}
+
+type PreviewPredicate struct {
+	Referent  string `json:"referent"`
+	Name      string `json:"name"`
+	PType     string `json:"p_type"`
+	PValue    int64  `json:"p_value"`
+	CredID    string `json:"cred_id"`
+	CredDefID string `json:"cred_def_id"`
+	SchemaID  string `json:"schema_id"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// ProtocolVersions holds the versions of the issue-credential and present-proof protocols used with a connection
+type ProtocolVersions struct {
+	IssueCredential string `json:"issue_credential"`
+	PresentProof    string `json:"present_proof"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"github.com/YasiruR/agent/domain"
+	"time"
+)
+
+// EnsureResult holds the identifiers of a credential definition and its schema to be used in offers, along with
+// whether each of them had to be created
+type EnsureResult struct {
+	Meta           domain.IndySchemaMeta `json:"meta"`
+	SchemaCreated  bool                  `json:"schema_created"`
+	CredDefCreated bool                  `json:"cred_def_created"`
+}
+
+// SchemaFamily is the lineage of the versions of a schema created by this agent
+type SchemaFamily struct {
+	Name     string          `json:"name"`
+	Versions []SchemaVersion `json:"versions"`
+}
+
+// SchemaVersion is a version of a schema family along with its credential definition, where the previous schema
+// is only known for versions created by evolving a schema
+type SchemaVersion struct {
+	Meta             domain.IndySchemaMeta `json:"meta"`
+	Attributes       []string              `json:"attributes"`
+	PreviousSchemaID string                `json:"previous_schema_id,omitempty"`
+	CreatedAt        time.Time             `json:"created_at"`
+}
+
+// Latest returns the highest version of the family
+func (f SchemaFamily) Latest() (SchemaVersion, bool) {
+	if len(f.Versions) == 0 {
+		return SchemaVersion{}, false
+	}
+
+	latest := f.Versions[0]
+	for _, v := range f.Versions[1:] {
+		if domain.CompareSchemaVersions(v.Meta.SchemaVersion, latest.Meta.SchemaVersion) > 0 {
+			latest = v
+		}
+	}
+
+	return latest, true
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ledger transaction types created by the controller
+const (
+	TxnTypeSchema  = `schema`
+	TxnTypeCredDef = `credential-definition`
+)
+
+// states of endorsed transactions which the controller reacts to
+const (
+	TxnStateCreated  = `transaction_created`
+	TxnStateEndorsed = `transaction_endorsed`
+	TxnStateRefused  = `transaction_refused`
+	TxnStateAcked    = `transaction_acked`
+)
+
+// Transaction is a ledger write of this agent (as an author) which needs to be endorsed before it is written
+type Transaction struct {
+	TransactionID string    `json:"transaction_id"`
+	ConnectionID  string    `json:"connection_id"`
+	Type          string    `json:"type"`
+	LedgerID      string    `json:"ledger_id"` // ID of the schema or credential definition to be written
+	State         string    `json:"state"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Endorser holds the connection and the DID of the endorser used by this agent as an author
+type Endorser struct {
+	Label        string `json:"label"`
+	ConnectionID string `json:"connection_id"`
+	Did          string `json:"endorser_did"`
+	Name         string `json:"endorser_name"`
+}
+
+// state of a transaction received by this agent as an endorser which awaits a decision
+const TxnStateRequestReceived = `request_received`
+
+// endorsement actions
+const (
+	EndorsementActionEndorse = `endorse`
+	EndorsementActionRefuse  = `refuse`
+)
+
+// EndorsementRequest is a transaction of an author awaiting endorsement by this agent, decoded from the ledger
+// request so that the reviewer can see what is going to be written
+type EndorsementRequest struct {
+	TransactionID string          `json:"transaction_id"`
+	ConnectionID  string          `json:"connection_id"`
+	AuthorLabel   string          `json:"author_label"`
+	AuthorDid     string          `json:"author_did"`
+	Type          string          `json:"type"`
+	Schema        *TxnSchema      `json:"schema,omitempty"`
+	CredDef       *TxnCredDef     `json:"credential_definition,omitempty"`
+	Operation     json.RawMessage `json:"operation"`
+	State         string          `json:"state"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+type TxnSchema struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	Attributes []string `json:"attributes"`
+}
+
+type TxnCredDef struct {
+	SchemaSeqNo   int    `json:"schema_seq_no"`
+	Tag           string `json:"tag"`
+	SignatureType string `json:"signature_type"`
+}
+
+// EndorsementPolicy decides on incoming endorsement requests automatically. Rules are evaluated in order and the
+// action of the first matching rule is taken, while requests matching no rule are left for manual review.
+type EndorsementPolicy struct {
+	Rules []EndorsementRule `json:"rules"`
+}
+
+// EndorsementRule matches requests by the DID of the author and the transaction type, where `*` or an empty value
+// matches any
+type EndorsementRule struct {
+	AuthorDid string   `json:"author_did"`
+	TxnTypes  []string `json:"txn_types"`
+	Action    string   `json:"action"`
+}
+
+func (p EndorsementPolicy) Validate() error {
+	for i, r := range p.Rules {
+		if r.Action != EndorsementActionEndorse && r.Action != EndorsementActionRefuse {
+			return fmt.Errorf(`invalid action '%s' in rule %d (should be %s or %s)`, r.Action, i, EndorsementActionEndorse, EndorsementActionRefuse)
+		}
+	}
+
+	return nil
+}
+
+// Action returns the action of the first rule matching the author DID and transaction type
+func (p EndorsementPolicy) Action(authorDid, txnType string) (action string, ok bool) {
+	for _, r := range p.Rules {
+		if r.AuthorDid != `` && r.AuthorDid != `*` && r.AuthorDid != authorDid {
+			continue
+		}
+
+		if !r.matchesType(txnType) {
+			continue
+		}
+
+		return r.Action, true
+	}
+
+	return ``, false
+}
+
+func (r EndorsementRule) matchesType(txnType string) bool {
+	if len(r.TxnTypes) == 0 {
+		return true
+	}
+
+	for _, t := range r.TxnTypes {
+		if t == `*` || t == txnType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/requests"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/agent/store"
+	"github.com/YasiruR/agent/domain"
+	"github.com/skip2/go-qrcode"
+	"sync"
+	"time"
+)
+
+// connectionless holds the connectionless exchanges by the thread ID of their initial message
+type connectionless struct {
+	exchanges map[string]models.ConnectionlessExchange
+	mu        *sync.Mutex
+	store     *store.File
+}
+
+func newConnectionless(dataDir string) *connectionless {
+	return &connectionless{
+		exchanges: make(map[string]models.ConnectionlessExchange),
+		mu:        &sync.Mutex{},
+		store:     store.NewFile(dataDir, `connectionless-exchanges.json`),
+	}
+}
+
+// attachment types of out-of-band invitations
+const (
+	attachTypeCredOffer    = `credential-offer`
+	attachTypePresentProof = `present-proof`
+	qrCodeSize             = 512
+)
+
+// retention of connectionless exchanges if no global retention is configured, since invitations which are never
+// accepted would otherwise be kept forever
+const connectionlessRetention = 7 * 24 * time.Hour
+
+// CreateConnectionlessOffer creates a credential offer which is not bound to any connection and wraps it in an
+// out-of-band invitation, where the credential is issued automatically once requested
+func (a *Agent) CreateConnectionlessOffer(cp domain.CredentialPreview, indySchema domain.IndySchemaMeta, opts models.OfferOptions) (models.ConnectionlessExchange, error) {
+	return a.createConnectionlessOffer(cp, indySchema, a.name, opts)
+}
+
+// CreateConnectionlessTemplateOffer is similar to CreateConnectionlessOffer except that the offer is constructed from
+// the stored template and the given attribute values
+func (a *Agent) CreateConnectionlessTemplateOffer(name string, values map[string]string, opts models.OfferOptions) (models.ConnectionlessExchange, error) {
+	cp, filter, comment, err := a.templateOffer(name, values)
+	if err != nil {
+		return models.ConnectionlessExchange{}, err
+	}
+
+	return a.createConnectionlessOffer(cp, filter, comment, opts)
+}
+
+func (a *Agent) createConnectionlessOffer(cp domain.CredentialPreview, indySchema domain.IndySchemaMeta, comment string, opts models.OfferOptions) (models.ConnectionlessExchange, error) {
+	req := requests.ConnectionlessOffer{AutoIssue: true, AutoRemove: a.retention.autoRemove, Comment: comment, CredentialPreview: cp}
+	req.Filter.Indy = indySchema
+	if opts.AutoRemove != nil {
+		req.AutoRemove = *opts.AutoRemove
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	res, err := a.post(a.adminUrl+endpointCreateOffer, data, `connectionless credential offer created`)
+	if err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`create offer - %v`, err)
+	}
+
+	var rec responses.CredExRecord
+	if err = json.Unmarshal(res, &rec); err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(res))
+	}
+
+	if opts.Retention != nil {
+		a.setRetention(rec.CredExID, *opts.Retention)
+	}
+	a.TrackExchange(models.ExchangeTypeCredential, rec.CredExID, `issuer`, ``)
+
+	return a.createConnectionlessExchange(models.ExchangeTypeCredential, attachTypeCredOffer, rec.CredExID, rec.ThreadID, rec.State)
+}
+
+// CreateConnectionlessProofRequest creates a present-proof v2.0 request which is not bound to any connection and
+// wraps it in an out-of-band invitation
+func (a *Agent) CreateConnectionlessProofRequest(pr domain.PresentationRequest, opts models.ProofRequestOptions) (models.ConnectionlessExchange, error) {
+	if err := a.validateProofRequestOptions(opts); err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`invalid options - %v`, err)
+	}
+
+	if err := a.prepareProofRequest(&pr); err != nil {
+		return models.ConnectionlessExchange{}, err
+	}
+
+	data, err := json.Marshal(requests.ConnectionlessProofRequest{Comment: a.name, PresentReq: pr})
+	if err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	res, err := a.post(a.adminUrl+endpointCreateProofReq, data, `connectionless proof request created`)
+	if err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`create request - %v`, err)
+	}
+
+	rec, err := parseExchangeRecord(models.ExchangeTypeProof, ProtocolV2, res)
+	if err != nil {
+		return models.ConnectionlessExchange{}, err
+	}
+
+	a.SetExchangeVersion(rec.ID, ProtocolV2)
+	a.TrackExchange(models.ExchangeTypeProof, rec.ID, `verifier`, ``)
+	a.enableAutoVerify(rec.ID, opts)
+
+	return a.createConnectionlessExchange(models.ExchangeTypeProof, attachTypePresentProof, rec.ID, rec.ThreadID, rec.State)
+}
+
+// CreateConnectionlessTemplateProofRequest is similar to CreateConnectionlessProofRequest except that the request is
+// rendered from the stored proof template with the given parameter values
+func (a *Agent) CreateConnectionlessTemplateProofRequest(name string, values map[string]interface{}, opts models.ProofRequestOptions) (models.ConnectionlessExchange, error) {
+	t, err := a.ProofTemplate(name)
+	if err != nil {
+		return models.ConnectionlessExchange{}, err
+	}
+
+	pr, err := t.Render(values)
+	if err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`render proof template - %v`, err)
+	}
+
+	return a.CreateConnectionlessProofRequest(pr, opts)
+}
+
+// createConnectionlessExchange wraps the exchange record in an out-of-band invitation and stores it for correlation
+func (a *Agent) createConnectionlessExchange(exType, attachType, exID, threadID, state string) (models.ConnectionlessExchange, error) {
+	inv, err := a.createOOBInvitation(requests.Attachment{ID: exID, Type: attachType})
+	if err != nil {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`create out-of-band invitation - %v`, err)
+	}
+
+	now := time.Now().UTC()
+	ex := models.ConnectionlessExchange{
+		Type:          exType,
+		ExchangeID:    exID,
+		ThreadID:      threadID,
+		State:         state,
+		InvitationURL: inv.InvitationURL,
+		Invitation:    inv.Invitation,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	a.oob.mu.Lock()
+	defer a.oob.mu.Unlock()
+
+	a.oob.exchanges[threadID] = ex
+	if err = a.oob.store.Save(a.oob.exchanges); err != nil {
+		delete(a.oob.exchanges, threadID)
+		return models.ConnectionlessExchange{}, fmt.Errorf(`persist connectionless exchange - %v`, err)
+	}
+
+	a.logger.Debug("connectionless exchange created", exType, exID, threadID)
+	return ex, nil
+}
+
+func (a *Agent) createOOBInvitation(attachment requests.Attachment) (*responses.OOBInvitation, error) {
+	body := requests.OOBInvitation{
+		Alias:        fmt.Sprintf("agent %s", a.name),
+		Attachments:  []requests.Attachment{attachment},
+		MyLabel:      a.name,
+		UsePublicDid: false,
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	res, err := a.post(a.adminUrl+endpointCreateOOBInv, data, fmt.Sprintf(`out-of-band invitation created for %s`, attachment.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	var inv responses.OOBInvitation
+	if err = json.Unmarshal(res, &inv); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(res))
+	}
+
+	return &inv, nil
+}
+
+// UpdateConnectionlessExchange correlates a webhook of the given thread with a connectionless exchange if exists and
+// updates its state. The exchange ID is updated as well since the agent of the peer may respond in a new record.
+func (a *Agent) UpdateConnectionlessExchange(threadID, exID, connID, state string) {
+	a.oob.mu.Lock()
+	defer a.oob.mu.Unlock()
+
+	ex, ok := a.oob.exchanges[threadID]
+	if !ok {
+		return
+	}
+
+	ex.ExchangeID = exID
+	ex.State = state
+	if connID != `` {
+		ex.ConnectionID = connID
+	}
+	ex.UpdatedAt = time.Now().UTC()
+
+	a.oob.exchanges[threadID] = ex
+	if err := a.oob.store.Save(a.oob.exchanges); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist connectionless exchange %s - %v`, threadID, err))
+	}
+
+	a.logger.Debug("connectionless exchange updated", ex.Type, threadID, state)
+}
+
+// ConnectionlessExchange returns the connectionless exchange by the thread ID of its initial message
+func (a *Agent) ConnectionlessExchange(threadID string) (models.ConnectionlessExchange, error) {
+	a.oob.mu.Lock()
+	defer a.oob.mu.Unlock()
+
+	ex, ok := a.oob.exchanges[threadID]
+	if !ok {
+		return models.ConnectionlessExchange{}, fmt.Errorf(`no connectionless exchange found for thread %s`, threadID)
+	}
+
+	return ex, nil
+}
+
+// ConnectionlessQR renders the invitation URL of the connectionless exchange as a PNG QR code. Invitations carrying
+// large attachments may exceed the capacity of a QR code in which case only the URL can be used.
+func (a *Agent) ConnectionlessQR(threadID string) ([]byte, error) {
+	ex, err := a.ConnectionlessExchange(threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	qr, err := qrcode.Encode(ex.InvitationURL, qrcode.Low, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf(`encode QR code of the invitation of %s - %v`, threadID, err)
+	}
+
+	return qr, nil
+}
+
+// pruneConnectionlessExchanges removes the connectionless exchanges which have not been updated within the retention,
+// either since they were completed or since the invitation was never accepted
+func (a *Agent) pruneConnectionlessExchanges() error {
+	retention := a.retention.period
+	if retention <= 0 {
+		retention = connectionlessRetention
+	}
+
+	a.oob.mu.Lock()
+	defer a.oob.mu.Unlock()
+
+	var pruned int
+	for threadID, ex := range a.oob.exchanges {
+		if time.Since(ex.UpdatedAt) > retention {
+			delete(a.oob.exchanges, threadID)
+			pruned++
+		}
+	}
+
+	if pruned == 0 {
+		return nil
+	}
+
+	if err := a.oob.store.Save(a.oob.exchanges); err != nil {
+		return fmt.Errorf(`persist connectionless exchanges - %v`, err)
+	}
+
+	a.logger.Debug(fmt.Sprintf(`pruned %d connectionless exchanges`, pruned))
+	return nil
+}
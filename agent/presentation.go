@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/requests"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/domain"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// candidatePageSize is the number of credentials fetched at once when searching for a credential of a referent
+const candidatePageSize = 50
+
+// constructProof selects a credential for each requested attribute and predicate, either as chosen by the holder
+// or by the selection policy, and returns the proof along with its preview
+func (a *Agent) constructProof(presExID string, pr domain.IndyProofRequest, pres domain.Presentation) (requests.ProofPresentation, models.ProofPreview, error) {
+	policy := a.SelectionPolicy()
+	if pres.Selection != nil {
+		policy = *pres.Selection
+	}
+
+	if err := policy.Validate(); err != nil {
+		return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`invalid selection policy - %v`, err)
+	}
+
+	for ref := range pres.Choices {
+		_, attr := pr.RequestedAttributes[ref]
+		_, pred := pr.RequestedPredicates[ref]
+		if !attr && !pred {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`credential is chosen for referent %s which is not requested`, ref)
+		}
+	}
+
+	unrevealed := make(map[string]bool)
+	for _, ref := range pres.Unrevealed {
+		attr, ok := pr.RequestedAttributes[ref]
+		if !ok {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`unrevealed attribute %s is not requested`, ref)
+		}
+
+		// attribute groups can only be presented as revealed
+		if len(attr.Names) > 0 {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`requested attribute group %s can not be unrevealed`, ref)
+		}
+
+		// allow_unrevealed is an extension of this controller which other verifiers do not send
+		if !attr.AllowUnrevealed && !a.selection.allowUnrevealed {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`verifier does not mark requested attribute %s with allow_unrevealed`, ref)
+		}
+		unrevealed[ref] = true
+	}
+
+	proof := requests.ProofPresentation{Indy: &requests.IndyPresentation{}}
+	proof.Indy.RequestedAttributes = make(map[string]requests.AdditionalProp)
+	proof.Indy.RequestedPredicates = make(map[string]requests.RequestedPredicate)
+	proof.Indy.SelfAttestedAttributes = make(map[string]string)
+	preview := models.ProofPreview{PresExID: presExID, Attributes: []models.PreviewAttribute{}, Predicates: []models.PreviewPredicate{}}
+	for reqAttrKey, reqAttr := range pr.RequestedAttributes {
+		cred, _, err := a.selectCredential(presExID, reqAttrKey, pres.Choices[reqAttrKey], policy, attributeAcceptor(reqAttr))
+		if err != nil {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`requested attribute %s - %v`, reqAttrKey, err)
+		}
+
+		if cred != nil {
+			revealed := !unrevealed[reqAttrKey]
+			proof.Indy.RequestedAttributes[reqAttrKey] = requests.AdditionalProp{CredID: cred.Referent, Revealed: revealed}
+			attr := models.PreviewAttribute{Referent: reqAttrKey, Names: reqAttr.AttributeNames(), CredID: cred.Referent,
+				CredDefID: cred.CredDefID, SchemaID: cred.SchemaID, Revealed: revealed, Values: map[string]string{},
+				VerifierLearns: models.DisclosureProven}
+			if revealed {
+				attr.VerifierLearns = models.DisclosureValue
+				for _, name := range attr.Names {
+					attr.Values[name], _ = credentialValue(*cred, name)
+				}
+			}
+			preview.Attributes = append(preview.Attributes, attr)
+			continue
+		}
+
+		if val, ok := pres.SelfAttestedValue(reqAttrKey, reqAttr); ok && len(reqAttr.Restrictions) == 0 {
+			if unrevealed[reqAttrKey] {
+				return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`self-attested attribute %s can not be unrevealed`, reqAttrKey)
+			}
+
+			proof.Indy.SelfAttestedAttributes[reqAttrKey] = val
+			preview.Attributes = append(preview.Attributes, models.PreviewAttribute{Referent: reqAttrKey, Names: reqAttr.AttributeNames(),
+				Revealed: true, SelfAttested: true, Values: map[string]string{reqAttr.Name: val}, VerifierLearns: models.DisclosureValue})
+			continue
+		}
+
+		names := strings.Join(reqAttr.AttributeNames(), `, `)
+		if len(reqAttr.Names) > 0 {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`requested attribute group %s (%s) is not held together by any credential meeting the restrictions`, reqAttrKey, names)
+		}
+
+		if len(reqAttr.Restrictions) == 0 {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`requested attribute %s (%s) is not held by any credential and no self-attested value is given`, reqAttrKey, names)
+		}
+		return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`requested attribute %s (%s) cannot be satisfied by any credential meeting the restrictions`, reqAttrKey, names)
+	}
+
+	for reqPredKey, reqPred := range pr.RequestedPredicates {
+		cred, err := a.satisfyPredicate(presExID, reqPredKey, pres.Choices[reqPredKey], policy, reqPred)
+		if err != nil {
+			return requests.ProofPresentation{}, models.ProofPreview{}, fmt.Errorf(`requested predicate %s (%s %s %d) cannot be satisfied - %v`,
+				reqPredKey, reqPred.Name, reqPred.PType, reqPred.PValue, err)
+		}
+
+		proof.Indy.RequestedPredicates[reqPredKey] = requests.RequestedPredicate{CredID: cred.Referent}
+		preview.Predicates = append(preview.Predicates, models.PreviewPredicate{Referent: reqPredKey, Name: reqPred.Name, PType: reqPred.PType,
+			PValue: reqPred.PValue, CredID: cred.Referent, CredDefID: cred.CredDefID, SchemaID: cred.SchemaID})
+	}
+
+	sort.Slice(preview.Attributes, func(i, j int) bool { return preview.Attributes[i].Referent < preview.Attributes[j].Referent })
+	sort.Slice(preview.Predicates, func(i, j int) bool { return preview.Predicates[i].Referent < preview.Predicates[j].Referent })
+
+	a.logger.Debug(`presentation proof constructed`, proof)
+	return proof, preview, nil
+}
+
+// satisfyPredicate returns the selected credential whose attribute value satisfies the predicate. Candidates are
+// counted at each check so that the error tells which of them rejected the credentials.
+func (a *Agent) satisfyPredicate(presExID, referent, choice string, policy domain.SelectionPolicy, pred domain.Predicate) (*responses.WalletCredential, error) {
+	if err := pred.Validate(); err != nil {
+		return nil, err
+	}
+
+	var restricted, satisfied int
+	accept := predicateAcceptor(pred)
+	cred, candidates, err := a.selectCredential(presExID, referent, choice, policy, func(cred responses.WalletCredential) bool {
+		if !meetsRestrictions(pred.Restrictions, cred) {
+			return false
+		}
+		restricted++
+
+		if !accept(cred) {
+			return false
+		}
+		satisfied++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cred != nil {
+		return cred, nil
+	}
+
+	switch {
+	case candidates == 0:
+		return nil, fmt.Errorf(`no credential in the wallet holds attribute %s`, pred.Name)
+	case restricted == 0:
+		return nil, fmt.Errorf(`none of the %d credentials holding attribute %s meets the restrictions`, candidates, pred.Name)
+	case satisfied == 0:
+		return nil, fmt.Errorf(`none of the %d credentials meeting the restrictions satisfies the predicate`, restricted)
+	}
+
+	return nil, fmt.Errorf(`none of the %d credentials satisfying the predicate can be used under the selection policy`, satisfied)
+}
+
+// selectCredential returns the credential used for the referent (nil if none can be used) along with the number
+// of candidates checked, either as chosen by the holder or by the selection policy
+func (a *Agent) selectCredential(presExID, referent, choice string, policy domain.SelectionPolicy, accept func(cred responses.WalletCredential) bool) (*responses.WalletCredential, int, error) {
+	if choice != `` {
+		var accepted bool
+		cred, checked, err := a.findCandidate(presExID, referent, func(cred responses.WalletCredential) bool {
+			if cred.Referent != choice {
+				return false
+			}
+			accepted = accept(cred)
+			return true
+		})
+		if err != nil {
+			return nil, checked, err
+		}
+
+		if cred == nil {
+			return nil, checked, fmt.Errorf(`chosen credential %s is not a candidate`, choice)
+		}
+
+		if !accepted {
+			return nil, checked, fmt.Errorf(`chosen credential %s does not satisfy the request`, choice)
+		}
+
+		return cred, checked, nil
+	}
+
+	if !policy.Ordered() {
+		var usableErr error
+		cred, checked, err := a.findCandidate(presExID, referent, func(cred responses.WalletCredential) bool {
+			if usableErr != nil || !accept(cred) {
+				return false
+			}
+
+			ok, err := a.usable(cred, policy)
+			if err != nil {
+				usableErr = err
+			}
+			return ok
+		})
+		if err == nil {
+			err = usableErr
+		}
+		return cred, checked, err
+	}
+
+	creds, checked, err := a.acceptedCandidates(presExID, referent, policy, accept)
+	if err != nil || len(creds) == 0 {
+		return nil, checked, err
+	}
+
+	return &a.rankCandidates(creds, policy)[0], checked, nil
+}
+
+// attributeAcceptor accepts credentials holding all attributes of the requested attribute or group which meet the
+// restrictions, so that all attributes of a group are proved by the same credential
+func attributeAcceptor(attr domain.Attribute) func(cred responses.WalletCredential) bool {
+	return func(cred responses.WalletCredential) bool {
+		return holdsAttributes(cred, attr.AttributeNames()) && meetsRestrictions(attr.Restrictions, cred)
+	}
+}
+
+// predicateAcceptor accepts credentials meeting the restrictions whose attribute value satisfies the predicate
+func predicateAcceptor(pred domain.Predicate) func(cred responses.WalletCredential) bool {
+	return func(cred responses.WalletCredential) bool {
+		if !meetsRestrictions(pred.Restrictions, cred) {
+			return false
+		}
+
+		// values which are not integers can not be used to prove predicates
+		satisfied, err := pred.SatisfiedBy(cred.Attrs[pred.Name])
+		return err == nil && satisfied
+	}
+}
+
+// holdsAttributes checks if the credential contains all the attributes
+func holdsAttributes(cred responses.WalletCredential, names []string) bool {
+	held := make(map[string]bool)
+	for name := range cred.Attrs {
+		held[domain.CanonicalAttribute(name)] = true
+	}
+
+	for _, name := range names {
+		if !held[domain.CanonicalAttribute(name)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// credentialValue returns the raw value of the attribute in the credential where names are compared in canonical form
+func credentialValue(cred responses.WalletCredential, name string) (string, bool) {
+	for attr, val := range cred.Attrs {
+		if domain.CanonicalAttribute(attr) == domain.CanonicalAttribute(name) {
+			return val, true
+		}
+	}
+
+	return ``, false
+}
+
+// meetsRestrictions checks if the credential meets any of the restrictions of a requested attribute or predicate
+func meetsRestrictions(restrictions []domain.Restriction, cred responses.WalletCredential) bool {
+	revRegID, _ := cred.RevRegID.(string)
+	return domain.MatchesAny(restrictions, cred.SchemaID, cred.CredDefID, revRegID, cred.Attrs)
+}
+
+// findCandidate pages through the candidate credentials of the referent until one is accepted, and returns it (nil
+// if none is accepted) along with the number of candidates checked
+func (a *Agent) findCandidate(presExID, referent string, accept func(cred responses.WalletCredential) bool) (*responses.WalletCredential, int, error) {
+	var checked int
+	for start := 0; ; start += candidatePageSize {
+		creds, err := a.candidateCredentials(presExID, referent, start, candidatePageSize)
+		if err != nil {
+			return nil, checked, err
+		}
+
+		for i := range creds {
+			checked++
+			if accept(creds[i]) {
+				return &creds[i], checked, nil
+			}
+		}
+
+		if len(creds) < candidatePageSize {
+			return nil, checked, nil
+		}
+	}
+}
+
+// candidateCredentials fetches a page of the credentials which the agent matches with the referent of the
+// presentation request
+func (a *Agent) candidateCredentials(presExID, referent string, start, count int) ([]responses.WalletCredential, error) {
+	_, p := a.exchangeProtocol(presExID)
+	params := url.Values{}
+	params.Add(`start`, strconv.Itoa(start))
+	params.Add(`count`, strconv.Itoa(count))
+	params.Add(`referent`, referent)
+
+	data, err := a.get(a.adminUrl+p.proofRecords+presExID+`/credentials?`+params.Encode(),
+		fmt.Sprintf(`fetched candidate credentials of %s for presentation exchange %s`, referent, presExID))
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []responses.CredentialMatch
+	if err = json.Unmarshal(data, &matches); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	creds := make([]responses.WalletCredential, 0, len(matches))
+	for _, m := range matches {
+		creds = append(creds, m.CredInfo)
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/domain"
+	"sort"
+)
+
+// CreateProofTemplate validates and persists a new proof request template
+func (a *Agent) CreateProofTemplate(t domain.ProofTemplate) error {
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf(`invalid template - %v`, err)
+	}
+
+	a.templates.proofMu.Lock()
+	defer a.templates.proofMu.Unlock()
+
+	if _, ok := a.templates.proofs.LoadOrStore(t.Name, t); ok {
+		return fmt.Errorf(`template %s already exists`, t.Name)
+	}
+
+	if err := a.saveProofTemplates(); err != nil {
+		a.templates.proofs.Delete(t.Name)
+		return err
+	}
+
+	a.logger.Debug("proof template created", t.Name)
+	return nil
+}
+
+// UpdateProofTemplate replaces an existing proof request template
+func (a *Agent) UpdateProofTemplate(t domain.ProofTemplate) error {
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf(`invalid template - %v`, err)
+	}
+
+	a.templates.proofMu.Lock()
+	defer a.templates.proofMu.Unlock()
+
+	old, err := a.ProofTemplate(t.Name)
+	if err != nil {
+		return err
+	}
+
+	a.templates.proofs.Store(t.Name, t)
+	if err = a.saveProofTemplates(); err != nil {
+		a.templates.proofs.Store(t.Name, old)
+		return err
+	}
+
+	a.logger.Debug("proof template updated", t.Name)
+	return nil
+}
+
+// DeleteProofTemplate removes the proof request template by its name
+func (a *Agent) DeleteProofTemplate(name string) error {
+	a.templates.proofMu.Lock()
+	defer a.templates.proofMu.Unlock()
+
+	old, err := a.ProofTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	a.templates.proofs.Delete(name)
+	if err = a.saveProofTemplates(); err != nil {
+		a.templates.proofs.Store(name, old)
+		return err
+	}
+
+	a.logger.Debug("proof template deleted", name)
+	return nil
+}
+
+func (a *Agent) ProofTemplate(name string) (domain.ProofTemplate, error) {
+	val, ok := a.templates.proofs.Load(name)
+	if !ok {
+		return domain.ProofTemplate{}, fmt.Errorf(`no proof template found for name %s`, name)
+	}
+
+	t, ok := val.(domain.ProofTemplate)
+	if !ok {
+		return domain.ProofTemplate{}, fmt.Errorf(`incompatible proof template found for name %s [%v]`, name, val)
+	}
+
+	return t, nil
+}
+
+// ProofTemplates returns all stored proof request templates sorted by name
+func (a *Agent) ProofTemplates() []domain.ProofTemplate {
+	list := []domain.ProofTemplate{}
+	a.templates.proofs.Range(func(_, val interface{}) bool {
+		if t, ok := val.(domain.ProofTemplate); ok {
+			list = append(list, t)
+		}
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// SendTemplateProofRequest sends the proof request rendered from the stored template with the given parameter
+// values, which override the defaults of the template
+func (a *Agent) SendTemplateProofRequest(name string, values map[string]interface{}, to string, opts models.ProofRequestOptions) (response []byte, err error) {
+	t, err := a.ProofTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := t.Render(values)
+	if err != nil {
+		return nil, fmt.Errorf(`render proof template - %v`, err)
+	}
+
+	return a.SendProofRequest(pr, to, opts)
+}
+
+func (a *Agent) loadProofTemplates() error {
+	var list []domain.ProofTemplate
+	if err := a.templates.proofStore.Load(&list); err != nil {
+		return err
+	}
+
+	for _, t := range list {
+		a.templates.proofs.Store(t.Name, t)
+	}
+
+	return nil
+}
+
+func (a *Agent) saveProofTemplates() error {
+	if err := a.templates.proofStore.Save(a.ProofTemplates()); err != nil {
+		return fmt.Errorf(`persist proof templates - %v`, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/agent/store"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// versions holds the protocol versions used with each connection and of each exchange
+type versions struct {
+	conns     map[string]models.ProtocolVersions // connection ID to protocol versions map
+	mu        *sync.Mutex
+	store     *store.File
+	exchanges *sync.Map // exchange ID to protocol version map
+}
+
+func newVersions(dataDir string) *versions {
+	return &versions{
+		conns:     make(map[string]models.ProtocolVersions),
+		mu:        &sync.Mutex{},
+		store:     store.NewFile(dataDir, `protocol-versions.json`),
+		exchanges: &sync.Map{},
+	}
+}
+
+// versions of issue-credential and present-proof protocols
+const (
+	ProtocolV1 = `1.0`
+	ProtocolV2 = `2.0`
+)
+
+const (
+	endpointDiscoverFeatures = `/discover-features/query`
+	credentialPreviewTypeV1  = `issue-credential/1.0/credential-preview`
+)
+
+// protocol holds the agent endpoints of a version of issue-credential and present-proof protocols
+type protocol struct {
+	sendOffer       string
+	sendCredAuto    string
+	createOffer     string
+	credRecords     string
+	credRecordList  string
+	sendProofReq    string
+	proofRecords    string
+	proofRecordList string
+}
+
+var protocols = map[string]protocol{
+	ProtocolV1: {
+		sendOffer:       `/issue-credential/send-offer`,
+		sendCredAuto:    `/issue-credential/send`,
+		createOffer:     `/issue-credential/create-offer`,
+		credRecords:     `/issue-credential/records/`,
+		credRecordList:  `/issue-credential/records`,
+		sendProofReq:    `/present-proof/send-request`,
+		proofRecords:    `/present-proof/records/`,
+		proofRecordList: `/present-proof/records`,
+	},
+	ProtocolV2: {
+		sendOffer:       endpointSendOffer,
+		sendCredAuto:    endpointSendCredAuto,
+		createOffer:     endpointCreateOffer,
+		credRecords:     endpointCredRecords,
+		credRecordList:  endpointCredRecordList,
+		sendProofReq:    endpointSendProofReq,
+		proofRecords:    endpointProofRecords,
+		proofRecordList: endpointProofRecordList,
+	},
+}
+
+// states of v1.0 exchanges which are named differently in v2.0
+var v1States = map[string]string{
+	`credential_acked`:   `done`,
+	`presentation_acked`: `done`,
+	`verified`:           `done`,
+}
+
+// NormalizeState converts a state of an exchange in the given protocol version to the corresponding v2.0 state so
+// that the rest of the controller is agnostic of the protocol version
+func NormalizeState(version, state string) string {
+	if version != ProtocolV1 {
+		return state
+	}
+
+	if s, ok := v1States[state]; ok {
+		return s
+	}
+
+	return strings.ReplaceAll(state, `_`, `-`)
+}
+
+// SetProtocolVersions explicitly sets the protocol versions to be used with the connection of the peer agent label.
+// Empty versions are left unchanged.
+func (a *Agent) SetProtocolVersions(label string, versions models.ProtocolVersions) (models.ProtocolVersions, error) {
+	connID, err := a.GetConnectionByLabel(label)
+	if err != nil {
+		return models.ProtocolVersions{}, fmt.Errorf(`get connection by label - %v`, err)
+	}
+
+	for _, v := range []string{versions.IssueCredential, versions.PresentProof} {
+		if _, ok := protocols[v]; v != `` && !ok {
+			return models.ProtocolVersions{}, fmt.Errorf(`unsupported protocol version %s`, v)
+		}
+	}
+
+	current := a.setConnProtocols(connID, versions)
+	a.logger.Debug("protocol versions set", label, current)
+	return current, nil
+}
+
+// ProtocolVersions returns the protocol versions used with the connection of the peer agent label
+func (a *Agent) ProtocolVersions(label string) (models.ProtocolVersions, error) {
+	connID, err := a.GetConnectionByLabel(label)
+	if err != nil {
+		return models.ProtocolVersions{}, fmt.Errorf(`get connection by label - %v`, err)
+	}
+
+	return a.connProtocols(connID), nil
+}
+
+// DiscoverProtocols queries the features supported by the peer agent via discover-features protocol. The disclosed
+// features are received by the webhook and then used to choose the protocol versions of the connection.
+func (a *Agent) DiscoverProtocols(label string) (response []byte, err error) {
+	connID, err := a.GetConnectionByLabel(label)
+	if err != nil {
+		return nil, fmt.Errorf(`get connection by label - %v`, err)
+	}
+
+	params := url.Values{}
+	params.Add(`connection_id`, connID)
+	params.Add(`query`, `*`)
+
+	return a.get(a.adminUrl+endpointDiscoverFeatures+`?`+params.Encode(), fmt.Sprintf(`discover features queried from %s`, label))
+}
+
+// SetDiscoveredProtocols chooses the latest versions of the protocols supported by both agents from the protocol
+// identifiers disclosed by the peer of the connection
+func (a *Agent) SetDiscoveredProtocols(connID string, pids []string) {
+	versions := models.ProtocolVersions{}
+	for _, pid := range pids {
+		for _, v := range []string{ProtocolV1, ProtocolV2} {
+			if strings.HasSuffix(pid, `issue-credential/`+v) && versions.IssueCredential < v {
+				versions.IssueCredential = v
+			}
+			if strings.HasSuffix(pid, `present-proof/`+v) && versions.PresentProof < v {
+				versions.PresentProof = v
+			}
+		}
+	}
+
+	if versions.IssueCredential == `` && versions.PresentProof == `` {
+		return
+	}
+
+	current := a.setConnProtocols(connID, versions)
+	a.logger.Debug("protocol versions discovered", connID, current)
+}
+
+// SetExchangeVersion records the protocol version of an exchange received by the webhook
+func (a *Agent) SetExchangeVersion(exID, version string) {
+	a.versions.exchanges.Store(exID, version)
+}
+
+func (a *Agent) connProtocols(connID string) models.ProtocolVersions {
+	a.versions.mu.Lock()
+	defer a.versions.mu.Unlock()
+
+	if v, ok := a.versions.conns[connID]; ok {
+		return v
+	}
+
+	return models.ProtocolVersions{IssueCredential: ProtocolV2, PresentProof: ProtocolV2}
+}
+
+// setConnProtocols updates the non-empty protocol versions of the connection and persists them so that peers
+// supporting only v1.0 are still handled after a restart
+func (a *Agent) setConnProtocols(connID string, versions models.ProtocolVersions) models.ProtocolVersions {
+	a.versions.mu.Lock()
+	defer a.versions.mu.Unlock()
+
+	current, ok := a.versions.conns[connID]
+	if !ok {
+		current = models.ProtocolVersions{IssueCredential: ProtocolV2, PresentProof: ProtocolV2}
+	}
+	if versions.IssueCredential != `` {
+		current.IssueCredential = versions.IssueCredential
+	}
+	if versions.PresentProof != `` {
+		current.PresentProof = versions.PresentProof
+	}
+
+	a.versions.conns[connID] = current
+	if err := a.versions.store.Save(a.versions.conns); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist protocol versions of %s - %v`, connID, err))
+	}
+
+	return current
+}
+
+// exchangeProtocol returns the endpoints of the protocol version of the given exchange where exchanges unknown to
+// the controller are assumed to be of v2.0
+func (a *Agent) exchangeProtocol(exID string) (version string, p protocol) {
+	version = ProtocolV2
+	if val, ok := a.versions.exchanges.Load(exID); ok {
+		if v, ok := val.(string); ok {
+			version = v
+		}
+	}
+
+	return version, protocols[version]
+}
+
+// exchangeRecord holds the fields of credential and presentation exchange records which are common to all
+// protocol versions
+type exchangeRecord struct {
+	ID        string
+	ConnID    string
+	ThreadID  string
+	State     string
+	UpdatedAt string
+}
+
+// parseExchangeRecord decodes a single credential or presentation exchange record of the given protocol version
+func parseExchangeRecord(exType, version string, data []byte) (exchangeRecord, error) {
+	var rec exchangeRecord
+	var err error
+	switch {
+	case exType == models.ExchangeTypeCredential && version == ProtocolV1:
+		var r responses.CredExRecordV1
+		err = json.Unmarshal(data, &r)
+		rec = exchangeRecord{ID: r.CredentialExchangeID, ConnID: r.ConnectionID, ThreadID: r.ThreadID, State: r.State, UpdatedAt: r.UpdatedAt}
+	case exType == models.ExchangeTypeCredential:
+		var r responses.CredExRecord
+		err = json.Unmarshal(data, &r)
+		rec = exchangeRecord{ID: r.CredExID, ConnID: r.ConnID, ThreadID: r.ThreadID, State: r.State, UpdatedAt: r.UpdatedAt}
+	case version == ProtocolV1:
+		var r responses.PresExRecordV1
+		err = json.Unmarshal(data, &r)
+		rec = exchangeRecord{ID: r.PresentationExchangeID, ConnID: r.ConnectionID, ThreadID: r.ThreadID, State: r.State, UpdatedAt: r.UpdatedAt}
+	default:
+		var r responses.PresExRecord
+		err = json.Unmarshal(data, &r)
+		rec = exchangeRecord{ID: r.PresExID, ConnID: r.ConnectionID, ThreadID: r.ThreadID, State: r.State, UpdatedAt: r.UpdatedAt}
+	}
+
+	if err != nil {
+		return exchangeRecord{}, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	rec.State = NormalizeState(version, rec.State)
+	return rec, nil
+}
+
+// parseExchangeRecords decodes a list of credential or presentation exchange records of the given protocol version
+func parseExchangeRecords(exType, version string, data []byte) ([]exchangeRecord, error) {
+	var res struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	var recs []exchangeRecord
+	for _, raw := range res.Results {
+		// v2.0 credential exchange records are wrapped along with their format specific records
+		if exType == models.ExchangeTypeCredential && version == ProtocolV2 {
+			var wrapped struct {
+				CredExRecord json.RawMessage `json:"cred_ex_record"`
+			}
+			if err := json.Unmarshal(raw, &wrapped); err != nil {
+				return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(raw))
+			}
+			raw = wrapped.CredExRecord
+		}
+
+		rec, err := parseExchangeRecord(exType, version, raw)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+
+	return recs, nil
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/tryfix/log"
+)
+
+func TestNormalizeState(t *testing.T) {
+	tests := []struct {
+		version string
+		state   string
+		want    string
+	}{
+		{version: ProtocolV1, state: `offer_sent`, want: `offer-sent`},
+		{version: ProtocolV1, state: `request_received`, want: `request-received`},
+		{version: ProtocolV1, state: `credential_acked`, want: `done`},
+		{version: ProtocolV1, state: `presentation_acked`, want: `done`},
+		{version: ProtocolV1, state: `verified`, want: `done`},
+		{version: ProtocolV1, state: `abandoned`, want: `abandoned`},
+		{version: ProtocolV2, state: `offer-sent`, want: `offer-sent`},
+		{version: ProtocolV2, state: `done`, want: `done`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.version+` `+tc.state, func(t *testing.T) {
+			if got := NormalizeState(tc.version, tc.state); got != tc.want {
+				t.Errorf(`got %s, want %s`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetDiscoveredProtocols(t *testing.T) {
+	tests := []struct {
+		name string
+		pids []string
+		want models.ProtocolVersions
+	}{
+		{
+			name: `nothing disclosed`,
+			pids: []string{`https://didcomm.org/basicmessage/1.0`},
+			want: models.ProtocolVersions{IssueCredential: ProtocolV2, PresentProof: ProtocolV2},
+		},
+		{
+			name: `v1.0 only`,
+			pids: []string{`https://didcomm.org/issue-credential/1.0`, `https://didcomm.org/present-proof/1.0`},
+			want: models.ProtocolVersions{IssueCredential: ProtocolV1, PresentProof: ProtocolV1},
+		},
+		{
+			name: `latest of both`,
+			pids: []string{`https://didcomm.org/issue-credential/2.0`, `https://didcomm.org/issue-credential/1.0`, `did:sov:BzCbsNYhMrjHiqZDTUASHg;spec/present-proof/1.0`},
+			want: models.ProtocolVersions{IssueCredential: ProtocolV2, PresentProof: ProtocolV1},
+		},
+	}
+
+	logger := log.Constructor.Log(log.WithLevel(`ERROR`))
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{Name: `holder`, DataDir: t.TempDir()}
+			a, err := New(cfg, logger)
+			if err != nil {
+				t.Fatalf(`create agent - %v`, err)
+			}
+
+			a.SetDiscoveredProtocols(`conn-1`, tc.pids)
+			if got := a.connProtocols(`conn-1`); got != tc.want {
+				t.Errorf(`got %+v, want %+v`, got, tc.want)
+			}
+
+			// versions should be restored from the data directory after a restart
+			if a, err = New(cfg, logger); err != nil {
+				t.Fatalf(`restart agent - %v`, err)
+			}
+
+			if got := a.connProtocols(`conn-1`); got != tc.want {
+				t.Errorf(`got %+v after restart, want %+v`, got, tc.want)
+			}
+		})
+	}
+}
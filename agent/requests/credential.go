@@ -12,3 +12,44 @@ type Offer struct {
 		Indy domain.IndySchemaMeta `json:"indy"`
 	} `json:"filter"`
 }
+
+// ConnectionlessOffer is the request body for creating an offer which is not bound to a connection
+type ConnectionlessOffer struct {
+	AutoIssue         bool                     `json:"auto_issue"`
+	AutoRemove        bool                     `json:"auto_remove"`
+	Comment           string                   `json:"comment"`
+	CredentialPreview domain.CredentialPreview `json:"credential_preview"`
+	Filter            struct {
+		Indy domain.IndySchemaMeta `json:"indy"`
+	} `json:"filter"`
+}
+
+// OfferV1 is the request body of credential offers in issue-credential v1.0
+type OfferV1 struct {
+	AutoRemove        bool                     `json:"auto_remove"`
+	Comment           string                   `json:"comment"`
+	ConnectionID      string                   `json:"connection_id"`
+	CredDefID         string                   `json:"cred_def_id"`
+	CredentialPreview domain.CredentialPreview `json:"credential_preview"`
+}
+
+// ProposalV1 is the request body of automated credential issuance in issue-credential v1.0
+type ProposalV1 struct {
+	AutoRemove         bool                     `json:"auto_remove"`
+	Comment            string                   `json:"comment"`
+	ConnectionID       string                   `json:"connection_id"`
+	CredDefID          string                   `json:"cred_def_id"`
+	CredentialProposal domain.CredentialPreview `json:"credential_proposal"`
+	IssuerDid          string                   `json:"issuer_did,omitempty"`
+	SchemaID           string                   `json:"schema_id,omitempty"`
+	SchemaIssuerDid    string                   `json:"schema_issuer_did,omitempty"`
+	SchemaName         string                   `json:"schema_name,omitempty"`
+	SchemaVersion      string                   `json:"schema_version,omitempty"`
+}
+
+type CredentialDef struct {
+	SchemaID               string `json:"schema_id"`
+	Tag                    string `json:"tag"`
+	SupportRevocation      bool   `json:"support_revocation"`
+	RevocationRegistrySize int    `json:"revocation_registry_size,omitempty"`
+}
@@ -1,14 +1,26 @@
 package requests
 
 type CreateInvitation struct {
-	Alias       string `json:"alias"`
-	Attachments []struct {
-		ID   string `json:"id"`
-		Type string `json:"type"`
-	} `json:"attachments"`
-	HandshakeProtocols []string `json:"handshake_protocols"`
-	MediationID        string   `json:"mediation_id"`
-	Metadata           struct{} `json:"metadata"`
-	MyLabel            string   `json:"my_label"`
-	UsePublicDid       bool     `json:"use_public_did"`
+	Alias              string       `json:"alias"`
+	Attachments        []Attachment `json:"attachments"`
+	HandshakeProtocols []string     `json:"handshake_protocols"`
+	MediationID        string       `json:"mediation_id"`
+	Metadata           struct{}     `json:"metadata"`
+	MyLabel            string       `json:"my_label"`
+	UsePublicDid       bool         `json:"use_public_did"`
+}
+
+// OOBInvitation is the request body of out-of-band invitations. Handshake protocols are omitted for connectionless
+// invitations which only carry attachments.
+type OOBInvitation struct {
+	Alias              string       `json:"alias"`
+	Attachments        []Attachment `json:"attachments"`
+	HandshakeProtocols []string     `json:"handshake_protocols,omitempty"`
+	MyLabel            string       `json:"my_label"`
+	UsePublicDid       bool         `json:"use_public_did"`
+}
+
+type Attachment struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
 }
@@ -8,16 +8,43 @@ type ProofRequest struct {
 	PresentReq   domain.PresentationRequest `json:"presentation_request"`
 }
 
+// ConnectionlessProofRequest is the request body for creating a proof request which is not bound to a connection
+type ConnectionlessProofRequest struct {
+	Comment    string                     `json:"comment"`
+	PresentReq domain.PresentationRequest `json:"presentation_request"`
+}
+
+// ProofPresentation holds the presentation in the format of the request
 type ProofPresentation struct {
-	Indy struct {
-		RequestedAttributes    map[string]AdditionalProp `json:"requested_attributes"`
-		RequestedPredicates    map[string]AdditionalProp `json:"requested_predicates"`
-		SelfAttestedAttributes map[string]string         `json:"self_attested_attributes"`
-		Trace                  bool                      `json:"trace"`
-	} `json:"indy"`
+	Indy *IndyPresentation `json:"indy,omitempty"`
+	Dif  *DIFPresentation  `json:"dif,omitempty"`
+}
+
+type IndyPresentation struct {
+	RequestedAttributes    map[string]AdditionalProp     `json:"requested_attributes"`
+	RequestedPredicates    map[string]RequestedPredicate `json:"requested_predicates"`
+	SelfAttestedAttributes map[string]string             `json:"self_attested_attributes"`
+	Trace                  bool                          `json:"trace"`
+}
+
+// DIFPresentation lets the agent select the credentials of the input descriptors except for those given by record IDs
+type DIFPresentation struct {
+	RecordIDs map[string][]string `json:"record_ids,omitempty"`
 }
 
 type AdditionalProp struct {
 	CredID   string `json:"cred_id"`
 	Revealed bool   `json:"revealed"`
 }
+
+// RequestedPredicate refers to the credential used to prove a predicate, which is never revealed
+type RequestedPredicate struct {
+	CredID string `json:"cred_id"`
+}
+
+// ProofRequestV1 is the request body of proof requests in present-proof v1.0
+type ProofRequestV1 struct {
+	Comment      string                  `json:"comment"`
+	ConnectionID string                  `json:"connection_id"`
+	ProofRequest domain.IndyProofRequest `json:"proof_request"`
+}
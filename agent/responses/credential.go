@@ -12,3 +12,29 @@ type WalletCredential struct {
 	RevRegID  interface{}       `json:"rev_reg_id"`
 	SchemaID  string            `json:"schema_id"`
 }
+
+// CredentialMatch is a credential of the wallet matched by the agent with the referents of a presentation request
+type CredentialMatch struct {
+	CredInfo              WalletCredential `json:"cred_info"`
+	PresentationReferents []string         `json:"presentation_referents"`
+}
+
+type CredExRecord struct {
+	ConnID    string `json:"conn_id"`
+	CreatedAt string `json:"created_at"`
+	CredExID  string `json:"cred_ex_id"`
+	Role      string `json:"role"`
+	State     string `json:"state"`
+	ThreadID  string `json:"thread_id"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type CredExRecordV1 struct {
+	ConnectionID         string `json:"connection_id"`
+	CreatedAt            string `json:"created_at"`
+	CredentialExchangeID string `json:"credential_exchange_id"`
+	Role                 string `json:"role"`
+	State                string `json:"state"`
+	ThreadID             string `json:"thread_id"`
+	UpdatedAt            string `json:"updated_at"`
+}
@@ -1,6 +1,9 @@
 package responses
 
-import "github.com/YasiruR/agent/domain"
+import (
+	"encoding/json"
+	"github.com/YasiruR/agent/domain"
+)
 
 type CreateInvitation struct {
 	ConnectionID  string            `json:"connection_id"`
@@ -8,6 +11,14 @@ type CreateInvitation struct {
 	InvitationURL string            `json:"invitation_url"`
 }
 
+type OOBInvitation struct {
+	InviMsgID     string          `json:"invi_msg_id"`
+	Invitation    json.RawMessage `json:"invitation"`
+	InvitationURL string          `json:"invitation_url"`
+	OobID         string          `json:"oob_id"`
+	State         string          `json:"state"`
+}
+
 type ReceiveInvitation struct {
 	Accept              string `json:"accept"`
 	Alias               string `json:"alias"`
@@ -1,6 +1,9 @@
 package responses
 
-import "github.com/YasiruR/agent/domain"
+import (
+	"encoding/json"
+	"github.com/YasiruR/agent/domain"
+)
 
 type PresentationProof struct {
 	AutoPresent bool `json:"auto_present"`
@@ -42,3 +45,113 @@ type PresentationProof struct {
 	Trace     bool   `json:"trace"`
 	UpdatedAt string `json:"updated_at"`
 }
+
+type PresExRecord struct {
+	ConnectionID string `json:"connection_id"`
+	CreatedAt    string `json:"created_at"`
+	PresExID     string `json:"pres_ex_id"`
+	Role         string `json:"role"`
+	State        string `json:"state"`
+	ThreadID     string `json:"thread_id"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+type PresExRecordV1 struct {
+	ConnectionID           string `json:"connection_id"`
+	CreatedAt              string `json:"created_at"`
+	PresentationExchangeID string `json:"presentation_exchange_id"`
+	Role                   string `json:"role"`
+	State                  string `json:"state"`
+	ThreadID               string `json:"thread_id"`
+	UpdatedAt              string `json:"updated_at"`
+}
+
+// VerifiedPresentation holds the fields of a verified presentation exchange record of either protocol version, where
+// v1.0 records hold the request and the proof at the top level
+type VerifiedPresentation struct {
+	PresExID               string   `json:"pres_ex_id"`
+	PresentationExchangeID string   `json:"presentation_exchange_id"`
+	State                  string   `json:"state"`
+	Verified               string   `json:"verified"`
+	VerifiedMsgs           []string `json:"verified_msgs"`
+	ByFormat               struct {
+		PresRequest struct {
+			Indy domain.IndyProofRequest `json:"indy"`
+		} `json:"pres_request"`
+		Pres struct {
+			Indy IndyProof               `json:"indy"`
+			Dif  *VerifiablePresentation `json:"dif"`
+		} `json:"pres"`
+	} `json:"by_format"`
+	PresentationRequest domain.IndyProofRequest `json:"presentation_request"`
+	Presentation        IndyProof               `json:"presentation"`
+}
+
+// IndyProof holds the parts of an indy proof disclosed to the verifier, where sub proof indexes refer to the
+// identifiers of the credentials used
+type IndyProof struct {
+	RequestedProof struct {
+		RevealedAttrs      map[string]RevealedAttr  `json:"revealed_attrs"`
+		RevealedAttrGroups map[string]RevealedGroup `json:"revealed_attr_groups"`
+		SelfAttestedAttrs  map[string]string        `json:"self_attested_attrs"`
+		UnrevealedAttrs    map[string]SubProof      `json:"unrevealed_attrs"`
+		Predicates         map[string]SubProof      `json:"predicates"`
+	} `json:"requested_proof"`
+	Identifiers []ProofIdentifier `json:"identifiers"`
+}
+
+type RevealedAttr struct {
+	SubProofIndex int    `json:"sub_proof_index"`
+	Raw           string `json:"raw"`
+}
+
+type RevealedGroup struct {
+	SubProofIndex int `json:"sub_proof_index"`
+	Values        map[string]struct {
+		Raw string `json:"raw"`
+	} `json:"values"`
+}
+
+type SubProof struct {
+	SubProofIndex int `json:"sub_proof_index"`
+}
+
+type ProofIdentifier struct {
+	SchemaID  string `json:"schema_id"`
+	CredDefID string `json:"cred_def_id"`
+	RevRegID  string `json:"rev_reg_id"`
+}
+
+// VerifiablePresentation is a W3C presentation where the submission maps the input descriptors to the credentials of
+// the presentation by JSON paths
+type VerifiablePresentation struct {
+	VerifiableCredential   []VerifiableCredential `json:"verifiableCredential"`
+	PresentationSubmission struct {
+		DefinitionID  string `json:"definition_id"`
+		DescriptorMap []struct {
+			ID     string `json:"id"`
+			Format string `json:"format"`
+			Path   string `json:"path"`
+		} `json:"descriptor_map"`
+	} `json:"presentation_submission"`
+}
+
+// VerifiableCredential is a W3C credential where the issuer is either an identifier or an object with the
+// identifier, and the subject is either an object or a list of objects
+type VerifiableCredential struct {
+	ID                string          `json:"id"`
+	Type              []string        `json:"type"`
+	Issuer            json.RawMessage `json:"issuer"`
+	IssuanceDate      string          `json:"issuanceDate"`
+	CredentialSubject json.RawMessage `json:"credentialSubject"`
+}
+
+// VCRecord is a W3C credential record of the wallet as returned for the credentials of a dif presentation request
+type VCRecord struct {
+	RecordID      string               `json:"record_id"`
+	GivenID       string               `json:"given_id"`
+	IssuerID      string               `json:"issuer_id"`
+	SchemaIDs     []string             `json:"schema_ids"`
+	ExpandedTypes []string             `json:"expanded_types"`
+	CredValue     VerifiableCredential `json:"cred_value"`
+}
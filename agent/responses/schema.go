@@ -0,0 +1,69 @@
+package responses
+
+import (
+	"encoding/json"
+	"github.com/YasiruR/agent/domain"
+)
+
+type Schema struct {
+	Schema   domain.LedgerSchema `json:"schema"`
+	SchemaID string              `json:"schema_id"`
+}
+
+type CreatedSchemas struct {
+	SchemaIDs []string `json:"schema_ids"`
+}
+
+type CreatedCredentialDefs struct {
+	CredentialDefinitionIDs []string `json:"credential_definition_ids"`
+}
+
+type CredentialDef struct {
+	CredentialDefinitionID string `json:"credential_definition_id"`
+}
+
+type PublicDid struct {
+	Result struct {
+		Did    string `json:"did"`
+		Verkey string `json:"verkey"`
+	} `json:"result"`
+}
+
+// Transaction is the transaction record created for an endorser
+type Transaction struct {
+	ConnectionID  string `json:"connection_id"`
+	CreatedAt     string `json:"created_at"`
+	State         string `json:"state"`
+	ThreadID      string `json:"thread_id"`
+	TransactionID string `json:"transaction_id"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// TransactionRecord is a transaction along with the ledger request to be endorsed
+type TransactionRecord struct {
+	Transaction
+	MessagesAttach []struct {
+		ID   string `json:"@id"`
+		Data struct {
+			JSON json.RawMessage `json:"json"`
+		} `json:"data"`
+	} `json:"messages_attach"`
+}
+
+type TransactionRecords struct {
+	Results []TransactionRecord `json:"results"`
+}
+
+// LedgerRequest is the part of an indy ledger request relevant for reviewing an endorsement
+type LedgerRequest struct {
+	Identifier string          `json:"identifier"`
+	Operation  json.RawMessage `json:"operation"`
+}
+
+type LedgerOperation struct {
+	Type          string          `json:"type"`
+	Data          json.RawMessage `json:"data"`
+	Ref           int             `json:"ref"`
+	SignatureType string          `json:"signature_type"`
+	Tag           string          `json:"tag"`
+}
@@ -0,0 +1,263 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/store"
+	"sync"
+	"time"
+)
+
+// timestamp layout used by ACA-Py for created_at and updated_at fields of records
+const acapyTimeLayout = `2006-01-02 15:04:05.999999Z`
+
+// exchange states after which the records are no longer needed by either party
+var (
+	credTerminalStates  = map[string]bool{`done`: true, `abandoned`: true, `credential-revoked`: true}
+	proofTerminalStates = map[string]bool{`done`: true, `abandoned`: true}
+)
+
+// retention holds the configuration of the record sweeper and the retention of offers overriding it
+type retention struct {
+	autoRemove bool
+	period     time.Duration
+	interval   time.Duration
+	overrides  map[string]time.Duration // credential exchange ID to retention map
+	mu         *sync.Mutex
+	store      *store.File
+}
+
+func newRetention(cfg Config) *retention {
+	return &retention{
+		autoRemove: cfg.AutoRemove,
+		period:     cfg.RecordRetention,
+		interval:   cfg.SweepInterval,
+		overrides:  make(map[string]time.Duration),
+		mu:         &sync.Mutex{},
+		store:      store.NewFile(cfg.DataDir, `retention-overrides.json`),
+	}
+}
+
+// RunSweeper periodically deletes completed and abandoned exchange records which have exceeded their retention
+// and prunes stale controller state. It blocks and should be started as a goroutine.
+func (a *Agent) RunSweeper() {
+	interval := a.retention.interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	a.logger.Info(fmt.Sprintf(`exchange record sweeper started with retention %s and interval %s`, a.retention.period, interval))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		a.sweepExchanges()
+		if err := a.pruneStoredCredentials(); err != nil {
+			a.logger.Error(fmt.Sprintf(`prune stored credentials - %v`, err))
+		}
+
+		<-ticker.C
+	}
+}
+
+// sweepExchanges deletes expired records if a global retention is configured or any offer overrides it
+func (a *Agent) sweepExchanges() {
+	if a.retention.period > 0 || a.hasRetentionOverrides() {
+		if err := a.sweepCredentialRecords(); err != nil {
+			a.logger.Error(fmt.Sprintf(`sweep credential records - %v`, err))
+		}
+	}
+
+	if a.retention.period > 0 {
+		if err := a.sweepPresentationRecords(); err != nil {
+			a.logger.Error(fmt.Sprintf(`sweep presentation records - %v`, err))
+		}
+	}
+
+	if err := a.pruneConnectionlessExchanges(); err != nil {
+		a.logger.Error(fmt.Sprintf(`prune connectionless exchanges - %v`, err))
+	}
+}
+
+func (a *Agent) sweepCredentialRecords() error {
+	// exchanges are taken before fetching the records so that those added by webhooks meanwhile are not mistaken
+	// for records removed from ACA-Py
+	known := make(map[string]bool)
+	a.credMap.Range(func(_, val interface{}) bool {
+		if credExID, ok := val.(string); ok {
+			known[credExID] = true
+		}
+		return true
+	})
+
+	existing := make(map[string]bool)
+	for version, p := range protocols {
+		data, err := a.get(a.adminUrl+p.credRecordList, fmt.Sprintf(`fetched credential exchange records of v%s`, version))
+		if err != nil {
+			return fmt.Errorf(`fetch records - %v`, err)
+		}
+
+		recs, err := parseExchangeRecords(models.ExchangeTypeCredential, version, data)
+		if err != nil {
+			return err
+		}
+
+		for _, rec := range recs {
+			existing[rec.ID] = true
+			retention, ok := a.credRetention(rec.ID)
+			if !ok || !credTerminalStates[rec.State] || !a.expired(rec.UpdatedAt, retention) {
+				continue
+			}
+
+			if _, err = a.delete(a.adminUrl+p.credRecords+rec.ID, fmt.Sprintf(`deleted credential exchange record %s [%s]`, rec.ID, rec.State)); err != nil {
+				a.logger.Error(fmt.Sprintf(`delete credential exchange record %s - %v`, rec.ID, err))
+				continue
+			}
+			delete(existing, rec.ID)
+			a.RemoveCredentialRecord(rec.ID)
+		}
+	}
+
+	// records removed from ACA-Py by other means should not be referred by the controller anymore
+	for credExID := range known {
+		if !existing[credExID] {
+			a.RemoveCredentialRecord(credExID)
+		}
+	}
+
+	return nil
+}
+
+func (a *Agent) sweepPresentationRecords() error {
+	known := make(map[string]bool)
+	a.proofMap.Range(func(_, val interface{}) bool {
+		if pp, ok := val.(models.ProofPresentation); ok {
+			known[pp.PresExID] = true
+		}
+		return true
+	})
+
+	existing := make(map[string]bool)
+	for version, p := range protocols {
+		data, err := a.get(a.adminUrl+p.proofRecordList, fmt.Sprintf(`fetched presentation exchange records of v%s`, version))
+		if err != nil {
+			return fmt.Errorf(`fetch records - %v`, err)
+		}
+
+		recs, err := parseExchangeRecords(models.ExchangeTypeProof, version, data)
+		if err != nil {
+			return err
+		}
+
+		for _, rec := range recs {
+			existing[rec.ID] = true
+			if !proofTerminalStates[rec.State] || !a.expired(rec.UpdatedAt, a.retention.period) {
+				continue
+			}
+
+			if _, err = a.delete(a.adminUrl+p.proofRecords+rec.ID, fmt.Sprintf(`deleted presentation exchange record %s [%s]`, rec.ID, rec.State)); err != nil {
+				a.logger.Error(fmt.Sprintf(`delete presentation exchange record %s - %v`, rec.ID, err))
+				continue
+			}
+			delete(existing, rec.ID)
+			a.RemovePresentationRecord(rec.ID)
+		}
+	}
+
+	for presExID := range known {
+		if !existing[presExID] {
+			a.RemovePresentationRecord(presExID)
+		}
+	}
+
+	return nil
+}
+
+// RemoveCredentialRecord removes all references of the credential exchange from the controller
+func (a *Agent) RemoveCredentialRecord(credExID string) {
+	a.dropRetention(credExID)
+	a.versions.exchanges.Delete(credExID)
+	a.ResolveExchange(credExID)
+	a.credMap.Range(func(label, val interface{}) bool {
+		if val == credExID {
+			a.credMap.Delete(label)
+			a.logger.Debug("credential record removed", label, credExID)
+		}
+		return true
+	})
+}
+
+// RemovePresentationRecord removes all references of the presentation exchange from the controller
+func (a *Agent) RemovePresentationRecord(presExID string) {
+	a.versions.exchanges.Delete(presExID)
+	a.ResolveExchange(presExID)
+	a.proofMap.Range(func(label, val interface{}) bool {
+		if pp, ok := val.(models.ProofPresentation); ok && pp.PresExID == presExID {
+			a.proofMap.Delete(label)
+			a.logger.Debug("proof record removed", label, presExID)
+		}
+		return true
+	})
+}
+
+// setRetention persists the retention of the credential exchange overriding the global one
+func (a *Agent) setRetention(credExID string, retention time.Duration) {
+	a.retention.mu.Lock()
+	defer a.retention.mu.Unlock()
+
+	a.retention.overrides[credExID] = retention
+	if err := a.retention.store.Save(a.retention.overrides); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist retention of %s - %v`, credExID, err))
+	}
+}
+
+func (a *Agent) dropRetention(credExID string) {
+	a.retention.mu.Lock()
+	defer a.retention.mu.Unlock()
+
+	if _, ok := a.retention.overrides[credExID]; !ok {
+		return
+	}
+
+	delete(a.retention.overrides, credExID)
+	if err := a.retention.store.Save(a.retention.overrides); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist retention of %s - %v`, credExID, err))
+	}
+}
+
+func (a *Agent) hasRetentionOverrides() bool {
+	a.retention.mu.Lock()
+	defer a.retention.mu.Unlock()
+	return len(a.retention.overrides) > 0
+}
+
+// credRetention returns the retention of the credential exchange and false if its record should be kept
+func (a *Agent) credRetention(credExID string) (time.Duration, bool) {
+	a.retention.mu.Lock()
+	defer a.retention.mu.Unlock()
+
+	if d, ok := a.retention.overrides[credExID]; ok {
+		return d, true
+	}
+
+	return a.retention.period, a.retention.period > 0
+}
+
+func (a *Agent) expired(updatedAt string, retention time.Duration) bool {
+	t, err := parseAcapyTime(updatedAt)
+	if err != nil {
+		a.logger.Warn(fmt.Sprintf(`invalid record timestamp %s - %v`, updatedAt, err))
+		return false
+	}
+
+	return time.Since(t) > retention
+}
+
+func parseAcapyTime(val string) (time.Time, error) {
+	t, err := time.Parse(acapyTimeLayout, val)
+	if err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339Nano, val)
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/domain"
+	"net/url"
+)
+
+// CreateSchema validates the schema and publishes it on the ledger via the agent, which needs to be a Trust Anchor
+// unless an endorser is configured. The published schema is cached for subsequent lookups.
+func (a *Agent) CreateSchema(schema domain.Schema) (response []byte, err error) {
+	if err = schema.Validate(); err != nil {
+		return nil, fmt.Errorf(`invalid schema - %v`, err)
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	res, err := a.postLedgerWrite(endpointSchemas, data, models.TxnTypeSchema, fmt.Sprintf("schema created %s:%s", schema.SchemaName, schema.SchemaVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	var created responses.Schema
+	if err = json.Unmarshal(res, &created); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(res))
+	}
+
+	if created.Schema.ID != `` {
+		a.schemaCache.Store(created.Schema.ID, created.Schema)
+	}
+
+	return res, nil
+}
+
+// CreatedSchemas lists the IDs of schemas created by this agent, optionally filtered by schema name and version
+func (a *Agent) CreatedSchemas(name, version string) ([]string, error) {
+	params := url.Values{}
+	if name != `` {
+		params.Add(`schema_name`, name)
+	}
+	if version != `` {
+		params.Add(`schema_version`, version)
+	}
+
+	data, err := a.get(a.adminUrl+endpointSchemas+`/created?`+params.Encode(), `fetched created schemas`)
+	if err != nil {
+		return nil, err
+	}
+
+	var res responses.CreatedSchemas
+	if err = json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	return res.SchemaIDs, nil
+}
+
+// Schema returns the schema by its ID from the cache or from the ledger if it has not been looked up before.
+// Schemas are immutable on the ledger, hence the cached entries never expire.
+func (a *Agent) Schema(schemaID string) (domain.LedgerSchema, error) {
+	if val, ok := a.schemaCache.Load(schemaID); ok {
+		if schema, ok := val.(domain.LedgerSchema); ok {
+			return schema, nil
+		}
+	}
+
+	data, err := a.get(a.adminUrl+endpointSchemas+`/`+url.PathEscape(schemaID), fmt.Sprintf(`fetched schema %s`, schemaID))
+	if err != nil {
+		return domain.LedgerSchema{}, err
+	}
+
+	var res responses.Schema
+	if err = json.Unmarshal(data, &res); err != nil {
+		return domain.LedgerSchema{}, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	if res.Schema.ID == `` {
+		return domain.LedgerSchema{}, fmt.Errorf(`schema %s not found on the ledger`, schemaID)
+	}
+
+	a.schemaCache.Store(schemaID, res.Schema)
+	return res.Schema, nil
+}
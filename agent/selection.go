@@ -0,0 +1,367 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/agent/store"
+	"github.com/YasiruR/agent/domain"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	endpointCredentials       = `/credentials`
+	endpointRevokedCredential = `/credential/revoked/`
+	walletPageSize            = 100
+)
+
+// ErrNoPresentationRecord is returned when no presentation request has been received with the exchange
+var ErrNoPresentationRecord = errors.New(`presentation record does not exist`)
+
+// selection holds the selection policy of credentials and the time at which each credential was stored
+type selection struct {
+	policy          domain.SelectionPolicy
+	mu              *sync.Mutex
+	store           *store.File
+	stored          map[string]time.Time // credential ID to the time at which it was stored in the wallet of this agent
+	storedMu        *sync.Mutex
+	storedLog       *store.Log
+	allowUnrevealed bool
+}
+
+func newSelection(cfg Config) *selection {
+	return &selection{
+		mu:              &sync.Mutex{},
+		store:           store.NewFile(cfg.DataDir, `selection-policy.json`),
+		stored:          make(map[string]time.Time),
+		storedMu:        &sync.Mutex{},
+		storedLog:       store.NewLog(cfg.DataDir, `stored-credentials.log`),
+		allowUnrevealed: cfg.AllowUnrevealed,
+	}
+}
+
+// storedCredential is an entry of the log of stored credentials where a removed entry supersedes the earlier one
+type storedCredential struct {
+	CredID   string    `json:"cred_id"`
+	StoredAt time.Time `json:"stored_at,omitempty"`
+	Removed  bool      `json:"removed,omitempty"`
+}
+
+// SelectionPolicy returns the policy used to select credentials for presentations unless overridden per presentation
+func (a *Agent) SelectionPolicy() domain.SelectionPolicy {
+	a.selection.mu.Lock()
+	defer a.selection.mu.Unlock()
+	return a.selection.policy
+}
+
+// SetSelectionPolicy validates and persists the policy used to select credentials for presentations
+func (a *Agent) SetSelectionPolicy(p domain.SelectionPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	a.selection.mu.Lock()
+	defer a.selection.mu.Unlock()
+
+	if err := a.selection.store.Save(p); err != nil {
+		return fmt.Errorf(`persist selection policy - %v`, err)
+	}
+
+	a.selection.policy = p
+	return nil
+}
+
+// RecordStoredCredential keeps the time at which a credential was stored in the wallet so that the newest
+// credentials can be selected for presentations
+func (a *Agent) RecordStoredCredential(credID string) {
+	a.selection.storedMu.Lock()
+	defer a.selection.storedMu.Unlock()
+
+	if _, ok := a.selection.stored[credID]; ok {
+		return
+	}
+
+	entry := storedCredential{CredID: credID, StoredAt: time.Now().UTC()}
+	if err := a.selection.storedLog.Append(entry); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist stored credential %s - %v`, credID, err))
+	}
+	a.selection.stored[credID] = entry.StoredAt
+}
+
+func (a *Agent) loadStoredCredentials() error {
+	return a.selection.storedLog.Read(func(line []byte) error {
+		var entry storedCredential
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf(`unmarshal error - %v [%s]`, err, string(line))
+		}
+
+		if entry.Removed {
+			delete(a.selection.stored, entry.CredID)
+			return nil
+		}
+
+		a.selection.stored[entry.CredID] = entry.StoredAt
+		return nil
+	})
+}
+
+// pruneStoredCredentials forgets the credentials which have been deleted from the wallet and compacts the log.
+// Credentials are taken before fetching the wallet so that those stored meanwhile are not mistaken for deleted ones.
+func (a *Agent) pruneStoredCredentials() error {
+	a.selection.storedMu.Lock()
+	known := make(map[string]bool, len(a.selection.stored))
+	for credID := range a.selection.stored {
+		known[credID] = true
+	}
+	a.selection.storedMu.Unlock()
+
+	if len(known) == 0 {
+		return nil
+	}
+
+	held, err := a.walletCredentialIDs()
+	if err != nil {
+		return fmt.Errorf(`fetch wallet credentials - %v`, err)
+	}
+
+	a.selection.storedMu.Lock()
+	defer a.selection.storedMu.Unlock()
+
+	var pruned int
+	for credID := range known {
+		if !held[credID] {
+			delete(a.selection.stored, credID)
+			pruned++
+		}
+	}
+
+	if pruned == 0 {
+		return nil
+	}
+
+	entries := make([]interface{}, 0, len(a.selection.stored))
+	for credID, t := range a.selection.stored {
+		entries = append(entries, storedCredential{CredID: credID, StoredAt: t})
+	}
+
+	if err = a.selection.storedLog.Replace(entries); err != nil {
+		return fmt.Errorf(`compact stored credentials - %v`, err)
+	}
+
+	a.logger.Debug(fmt.Sprintf(`forgot %d credentials deleted from the wallet`, pruned))
+	return nil
+}
+
+// walletCredentialIDs pages through the credentials of the wallet and returns their IDs
+func (a *Agent) walletCredentialIDs() (map[string]bool, error) {
+	ids := make(map[string]bool)
+	for start := 0; ; start += walletPageSize {
+		params := url.Values{}
+		params.Add(`start`, strconv.Itoa(start))
+		params.Add(`count`, strconv.Itoa(walletPageSize))
+
+		data, err := a.get(a.adminUrl+endpointCredentials+`?`+params.Encode(), `fetched wallet credentials`)
+		if err != nil {
+			return nil, err
+		}
+
+		var res responses.Credentials
+		if err = json.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+		}
+
+		for _, cred := range res.Results {
+			ids[cred.Referent] = true
+		}
+
+		if len(res.Results) < walletPageSize {
+			return ids, nil
+		}
+	}
+}
+
+func (a *Agent) storedAt(credID string) (time.Time, bool) {
+	a.selection.storedMu.Lock()
+	defer a.selection.storedMu.Unlock()
+
+	t, ok := a.selection.stored[credID]
+	return t, ok
+}
+
+// Candidates lists the credentials which can be used for each referent of the presentation request received with
+// the exchange, in the order in which they would be selected
+func (a *Agent) Candidates(presExID string) (models.Candidates, error) {
+	req, err := a.presentationRecordByID(presExID)
+	if err != nil {
+		return models.Candidates{}, err
+	}
+
+	res := models.Candidates{PresExID: presExID, Attributes: make(map[string][]models.Candidate), Predicates: make(map[string][]models.Candidate)}
+	if req.Dif != nil {
+		if res.Descriptors, err = a.descriptorCandidates(presExID, *req.Dif); err != nil {
+			return models.Candidates{}, fmt.Errorf(`matching records - %v`, err)
+		}
+		return res, nil
+	}
+
+	if req.Indy == nil {
+		return models.Candidates{}, fmt.Errorf(`presentation request of exchange %s is in an unsupported format`, presExID)
+	}
+
+	pr := *req.Indy
+	policy := a.SelectionPolicy()
+	for ref, attr := range pr.RequestedAttributes {
+		creds, _, err := a.acceptedCandidates(presExID, ref, policy, attributeAcceptor(attr))
+		if err != nil {
+			return models.Candidates{}, fmt.Errorf(`requested attribute %s - %v`, ref, err)
+		}
+		res.Attributes[ref] = a.toCandidates(a.rankCandidates(creds, policy))
+	}
+
+	for ref, pred := range pr.RequestedPredicates {
+		if err = pred.Validate(); err != nil {
+			return models.Candidates{}, fmt.Errorf(`requested predicate %s - %v`, ref, err)
+		}
+
+		creds, _, err := a.acceptedCandidates(presExID, ref, policy, predicateAcceptor(pred))
+		if err != nil {
+			return models.Candidates{}, fmt.Errorf(`requested predicate %s - %v`, ref, err)
+		}
+		res.Predicates[ref] = a.toCandidates(a.rankCandidates(creds, policy))
+	}
+
+	return res, nil
+}
+
+// acceptedCandidates pages through all candidates of the referent and returns those accepted and usable under the
+// policy along with the number of candidates checked
+func (a *Agent) acceptedCandidates(presExID, referent string, policy domain.SelectionPolicy, accept func(cred responses.WalletCredential) bool) ([]responses.WalletCredential, int, error) {
+	var creds []responses.WalletCredential
+	var usableErr error
+	_, checked, err := a.findCandidate(presExID, referent, func(cred responses.WalletCredential) bool {
+		if usableErr != nil || !accept(cred) {
+			return false
+		}
+
+		ok, err := a.usable(cred, policy)
+		if err != nil {
+			usableErr = err
+			return false
+		}
+
+		if ok {
+			creds = append(creds, cred)
+		}
+		// all candidates are collected so that they can be ranked
+		return false
+	})
+	if err != nil {
+		return nil, checked, err
+	}
+
+	return creds, checked, usableErr
+}
+
+// usable checks if the credential can be used under the policy where revoked credentials are excluded if required
+func (a *Agent) usable(cred responses.WalletCredential, policy domain.SelectionPolicy) (bool, error) {
+	if !policy.NonRevokedOnly {
+		return true, nil
+	}
+
+	if revRegID, _ := cred.RevRegID.(string); revRegID == `` {
+		return true, nil
+	}
+
+	revoked, err := a.revoked(cred.Referent)
+	if err != nil {
+		return false, fmt.Errorf(`check revocation status of credential %s - %v`, cred.Referent, err)
+	}
+
+	return !revoked, nil
+}
+
+func (a *Agent) revoked(credID string) (bool, error) {
+	data, err := a.get(a.adminUrl+endpointRevokedCredential+credID, fmt.Sprintf(`fetched revocation status of credential %s`, credID))
+	if err != nil {
+		return false, err
+	}
+
+	var res struct {
+		Revoked bool `json:"revoked"`
+	}
+	if err = json.Unmarshal(data, &res); err != nil {
+		return false, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	return res.Revoked, nil
+}
+
+// rankCandidates orders the credentials by the preferred issuers first and then by the strategy, where credentials
+// with the same rank keep the order of the agent
+func (a *Agent) rankCandidates(creds []responses.WalletCredential, policy domain.SelectionPolicy) []responses.WalletCredential {
+	issuerRank := func(cred responses.WalletCredential) int {
+		issuer := strings.SplitN(cred.CredDefID, `:`, 2)[0]
+		for i, did := range policy.PreferredIssuers {
+			if did == issuer {
+				return i
+			}
+		}
+		return len(policy.PreferredIssuers)
+	}
+
+	sort.SliceStable(creds, func(i, j int) bool {
+		if ri, rj := issuerRank(creds[i]), issuerRank(creds[j]); ri != rj {
+			return ri < rj
+		}
+
+		if policy.Strategy != domain.SelectionNewest {
+			return false
+		}
+
+		// credentials stored before they were tracked are considered to be the oldest
+		ti, _ := a.storedAt(creds[i].Referent)
+		tj, _ := a.storedAt(creds[j].Referent)
+		return ti.After(tj)
+	})
+
+	return creds
+}
+
+func (a *Agent) toCandidates(creds []responses.WalletCredential) []models.Candidate {
+	list := make([]models.Candidate, 0, len(creds))
+	for _, cred := range creds {
+		c := models.Candidate{CredID: cred.Referent, CredDefID: cred.CredDefID, SchemaID: cred.SchemaID, Attrs: cred.Attrs}
+		c.RevRegID, _ = cred.RevRegID.(string)
+		if t, ok := a.storedAt(cred.Referent); ok {
+			c.StoredAt = &t
+		}
+		list = append(list, c)
+	}
+
+	return list
+}
+
+// presentationRecordByID returns the presentation request stored by the webhook for the exchange
+func (a *Agent) presentationRecordByID(presExID string) (domain.PresentationRequest, error) {
+	var pr *domain.PresentationRequest
+	a.proofMap.Range(func(_, val interface{}) bool {
+		if pp, ok := val.(models.ProofPresentation); ok && pp.PresExID == presExID {
+			pr = &pp.PresReq
+			return false
+		}
+		return true
+	})
+
+	if pr == nil {
+		return domain.PresentationRequest{}, fmt.Errorf(`%w for exchange %s`, ErrNoPresentationRecord, presExID)
+	}
+
+	return *pr, nil
+}
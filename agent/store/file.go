@@ -0,0 +1,67 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File persists a JSON encoded value in a single file under the data directory of the controller
+type File struct {
+	path string
+	mu   *sync.Mutex
+}
+
+func NewFile(dir, name string) *File {
+	return &File{path: filepath.Join(dir, name), mu: &sync.Mutex{}}
+}
+
+// Load decodes the stored value into v and leaves v untouched if nothing has been stored yet
+func (f *File) Load(v interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf(`reading file - %v`, err)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf(`unmarshal error - %v [%s]`, err, f.path)
+	}
+
+	return nil
+}
+
+// Save replaces the stored value with v. Data is written to a temporary file first so that a failed write
+// does not corrupt the existing content.
+func (f *File) Save(v interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := json.MarshalIndent(v, ``, `  `)
+	if err != nil {
+		return fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf(`creating directory - %v`, err)
+	}
+
+	tmp := f.path + `.tmp`
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf(`writing file - %v`, err)
+	}
+
+	if err = os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf(`replacing file - %v`, err)
+	}
+
+	return nil
+}
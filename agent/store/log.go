@@ -0,0 +1,111 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Log is an append-only file where each entry is stored as a single line of JSON
+type Log struct {
+	path string
+	mu   *sync.Mutex
+}
+
+func NewLog(dir, name string) *Log {
+	return &Log{path: filepath.Join(dir, name), mu: &sync.Mutex{}}
+}
+
+// Append writes v as a new line at the end of the log and syncs it to the disk
+func (l *Log) Append(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf(`creating directory - %v`, err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf(`opening file - %v`, err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf(`writing file - %v`, err)
+	}
+
+	return f.Sync()
+}
+
+// Read calls fn for each line of the log in the order of insertion and stops at the first error
+func (l *Log) Read(fn func(line []byte) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf(`opening file - %v`, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err = fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf(`reading file - %v`, err)
+	}
+
+	return nil
+}
+
+// Replace atomically replaces the entries of the log, which allows compacting logs whose entries supersede earlier
+// ones. Data is written to a temporary file first so that a failed write does not corrupt the existing log.
+func (l *Log) Replace(entries []interface{}) error {
+	var data []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf(`marshal error - %v`, err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf(`creating directory - %v`, err)
+	}
+
+	tmp := l.path + `.tmp`
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf(`writing file - %v`, err)
+	}
+
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf(`replacing file - %v`, err)
+	}
+
+	return nil
+}
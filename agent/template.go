@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/YasiruR/agent/agent/store"
+	"github.com/YasiruR/agent/domain"
+	"sort"
+	"sync"
+)
+
+// templates holds the credential offer and proof request templates by name
+type templates struct {
+	offers     *sync.Map
+	offerStore *store.File
+	offerMu    *sync.Mutex
+	proofs     *sync.Map
+	proofStore *store.File
+	proofMu    *sync.Mutex
+}
+
+func newTemplates(dataDir string) *templates {
+	return &templates{
+		offers:     &sync.Map{},
+		offerStore: store.NewFile(dataDir, `offer-templates.json`),
+		offerMu:    &sync.Mutex{},
+		proofs:     &sync.Map{},
+		proofStore: store.NewFile(dataDir, `proof-templates.json`),
+		proofMu:    &sync.Mutex{},
+	}
+}
+
+// CreateOfferTemplate validates and persists a new credential offer template
+func (a *Agent) CreateOfferTemplate(t domain.OfferTemplate) error {
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf(`invalid template - %v`, err)
+	}
+
+	a.templates.offerMu.Lock()
+	defer a.templates.offerMu.Unlock()
+
+	if _, ok := a.templates.offers.LoadOrStore(t.Name, t); ok {
+		return fmt.Errorf(`template %s already exists`, t.Name)
+	}
+
+	if err := a.saveOfferTemplates(); err != nil {
+		a.templates.offers.Delete(t.Name)
+		return err
+	}
+
+	a.logger.Debug("offer template created", t.Name)
+	return nil
+}
+
+// UpdateOfferTemplate replaces an existing credential offer template
+func (a *Agent) UpdateOfferTemplate(t domain.OfferTemplate) error {
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf(`invalid template - %v`, err)
+	}
+
+	a.templates.offerMu.Lock()
+	defer a.templates.offerMu.Unlock()
+
+	old, err := a.OfferTemplate(t.Name)
+	if err != nil {
+		return err
+	}
+
+	a.templates.offers.Store(t.Name, t)
+	if err = a.saveOfferTemplates(); err != nil {
+		a.templates.offers.Store(t.Name, old)
+		return err
+	}
+
+	a.logger.Debug("offer template updated", t.Name)
+	return nil
+}
+
+// DeleteOfferTemplate removes the credential offer template by its name
+func (a *Agent) DeleteOfferTemplate(name string) error {
+	a.templates.offerMu.Lock()
+	defer a.templates.offerMu.Unlock()
+
+	old, err := a.OfferTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	a.templates.offers.Delete(name)
+	if err = a.saveOfferTemplates(); err != nil {
+		a.templates.offers.Store(name, old)
+		return err
+	}
+
+	a.logger.Debug("offer template deleted", name)
+	return nil
+}
+
+func (a *Agent) OfferTemplate(name string) (domain.OfferTemplate, error) {
+	val, ok := a.templates.offers.Load(name)
+	if !ok {
+		return domain.OfferTemplate{}, fmt.Errorf(`no offer template found for name %s`, name)
+	}
+
+	t, ok := val.(domain.OfferTemplate)
+	if !ok {
+		return domain.OfferTemplate{}, fmt.Errorf(`incompatible offer template found for name %s [%v]`, name, val)
+	}
+
+	return t, nil
+}
+
+// OfferTemplates returns all stored credential offer templates sorted by name
+func (a *Agent) OfferTemplates() []domain.OfferTemplate {
+	var list []domain.OfferTemplate
+	a.templates.offers.Range(func(_, val interface{}) bool {
+		if t, ok := val.(domain.OfferTemplate); ok {
+			list = append(list, t)
+		}
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// templateOffer constructs the contents of an offer from the stored template and the given attribute values
+func (a *Agent) templateOffer(name string, values map[string]string) (cp domain.CredentialPreview, filter domain.IndySchemaMeta, comment string, err error) {
+	t, err := a.OfferTemplate(name)
+	if err != nil {
+		return cp, filter, ``, err
+	}
+
+	filter = t.Filter
+	if t.SchemaFamily != `` {
+		latest, err := a.latestOfFamily(t.SchemaFamily)
+		if err != nil {
+			return cp, filter, ``, fmt.Errorf(`resolve schema family - %v`, err)
+		}
+
+		filter = latest.Meta
+		if len(t.Attributes) == 0 {
+			t.Attributes = latest.Attributes
+		}
+	}
+
+	cp, err = t.Preview(values)
+	if err != nil {
+		return cp, filter, ``, fmt.Errorf(`credential preview - %v`, err)
+	}
+
+	comment = t.Comment
+	if comment == `` {
+		comment = a.name
+	}
+
+	return cp, filter, comment, nil
+}
+
+func (a *Agent) loadOfferTemplates() error {
+	var list []domain.OfferTemplate
+	if err := a.templates.offerStore.Load(&list); err != nil {
+		return err
+	}
+
+	for _, t := range list {
+		a.templates.offers.Store(t.Name, t)
+	}
+
+	return nil
+}
+
+func (a *Agent) saveOfferTemplates() error {
+	if err := a.templates.offerStore.Save(a.OfferTemplates()); err != nil {
+		return fmt.Errorf(`persist offer templates - %v`, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,375 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/agent/responses"
+	"github.com/YasiruR/agent/agent/store"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// formats of presentations
+const (
+	formatIndy = `indy`
+	formatDIF  = `dif`
+)
+
+// callbacks of verification records are retried at the interval until they are accepted or the attempts run out
+const (
+	callbackTimeout       = 10 * time.Second
+	callbackRetryInterval = time.Minute
+	maxCallbackAttempts   = 10
+)
+
+// verification holds the verification records of this agent as a verifier and the delivery of their callbacks
+type verification struct {
+	records       map[string]models.VerificationRecord // presentation exchange ID to verification record map
+	mu            *sync.Mutex
+	store         *store.File
+	running       *sync.Map // presentation exchange IDs being verified automatically
+	client        *http.Client
+	callbackHosts []string
+}
+
+func newVerification(cfg Config) *verification {
+	return &verification{
+		records:       make(map[string]models.VerificationRecord),
+		mu:            &sync.Mutex{},
+		store:         store.NewFile(cfg.DataDir, `verifications.json`),
+		running:       &sync.Map{},
+		client:        &http.Client{Timeout: callbackTimeout},
+		callbackHosts: cfg.CallbackHosts,
+	}
+}
+
+// submissionPath matches the paths of the presentation submission referring to the credentials of the presentation
+var submissionPath = regexp.MustCompile(`^\$\.verifiableCredential\[(\d+)\]$`)
+
+// VerifyProof verifies the presentation received for the exchange and returns the outcome, which is also stored
+// as the verification record of the exchange
+func (a *Agent) VerifyProof(presExID string) (models.VerificationResult, error) {
+	res, err := a.verifyPresentation(presExID)
+	a.recordVerification(presExID, res, err)
+	return res, err
+}
+
+// AutoVerify verifies the presentation of an exchange requested with auto verification once, and posts the
+// outcome to the callback URL if given
+func (a *Agent) AutoVerify(presExID string) {
+	if _, busy := a.verification.running.LoadOrStore(presExID, true); busy {
+		return
+	}
+	defer a.verification.running.Delete(presExID)
+
+	// the record is read while holding the guard so that a verification completed meanwhile is not repeated
+	rec, ok := a.Verification(presExID)
+	if !ok || !rec.AutoVerify || rec.VerifiedAt != nil {
+		return
+	}
+
+	res, err := a.verifyPresentation(presExID)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf(`auto verify presentation %s - %v`, presExID, err))
+	} else {
+		a.logger.Info(fmt.Sprintf(`presentation %s verified automatically [verified: %t]`, presExID, res.Verified))
+	}
+
+	rec = a.recordVerification(presExID, res, err)
+	if rec.CallbackURL == `` {
+		return
+	}
+	a.postVerification(rec)
+}
+
+// RunCallbackRetrier periodically posts the verification records which could not be delivered to their callbacks.
+// It blocks and should be started as a goroutine.
+func (a *Agent) RunCallbackRetrier() {
+	ticker := time.NewTicker(callbackRetryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var undelivered []models.VerificationRecord
+		a.verification.mu.Lock()
+		for _, rec := range a.verification.records {
+			if rec.CallbackURL != `` && rec.VerifiedAt != nil && !rec.Notified && rec.NotifyAttempts < maxCallbackAttempts {
+				undelivered = append(undelivered, rec)
+			}
+		}
+		a.verification.mu.Unlock()
+
+		for _, rec := range undelivered {
+			a.postVerification(rec)
+		}
+	}
+}
+
+// postVerification posts the verification record to its callback URL and stores whether it was delivered
+func (a *Agent) postVerification(rec models.VerificationRecord) {
+	rec.NotifyAttempts++
+	if err := a.notifyVerification(rec); err != nil {
+		a.logger.Error(fmt.Sprintf(`post verification of %s to callback [attempt %d/%d] - %v`, rec.PresExID, rec.NotifyAttempts, maxCallbackAttempts, err))
+	} else {
+		rec.Notified = true
+	}
+
+	if err := a.saveVerification(rec); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist verification record of %s - %v`, rec.PresExID, err))
+	}
+}
+
+// parseDIFVerification adds the credential submitted for each input descriptor of a dif presentation to the result
+func parseDIFVerification(result models.VerificationResult, vp responses.VerifiablePresentation) (models.VerificationResult, error) {
+	result.Format = formatDIF
+	result.Attributes, result.Predicates, result.SelfAttested = []models.VerifiedAttribute{}, []models.VerifiedPredicate{}, []models.SelfAttestedAttribute{}
+	result.Descriptors = []models.VerifiedDescriptor{}
+	for _, d := range vp.PresentationSubmission.DescriptorMap {
+		m := submissionPath.FindStringSubmatch(d.Path)
+		if m == nil {
+			return models.VerificationResult{}, fmt.Errorf(`unsupported submission path %s of input descriptor %s`, d.Path, d.ID)
+		}
+
+		i, _ := strconv.Atoi(m[1])
+		if i >= len(vp.VerifiableCredential) {
+			return models.VerificationResult{}, fmt.Errorf(`submission path %s of input descriptor %s does not refer to a credential`, d.Path, d.ID)
+		}
+
+		cred, err := verifiedCredential(vp.VerifiableCredential[i])
+		if err != nil {
+			return models.VerificationResult{}, fmt.Errorf(`credential of input descriptor %s - %v`, d.ID, err)
+		}
+		result.Descriptors = append(result.Descriptors, models.VerifiedDescriptor{ID: d.ID, Format: d.Format, Credential: cred})
+	}
+
+	sort.Slice(result.Descriptors, func(i, j int) bool { return result.Descriptors[i].ID < result.Descriptors[j].ID })
+	return result, nil
+}
+
+func verifiedCredential(vc responses.VerifiableCredential) (models.VerifiedCredential, error) {
+	cred := models.VerifiedCredential{ID: vc.ID, Types: vc.Type, IssuanceDate: vc.IssuanceDate}
+	if len(vc.Issuer) > 0 && json.Unmarshal(vc.Issuer, &cred.Issuer) != nil {
+		var issuer struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(vc.Issuer, &issuer); err != nil {
+			return models.VerifiedCredential{}, fmt.Errorf(`unmarshal error of issuer - %v [%s]`, err, string(vc.Issuer))
+		}
+		cred.Issuer = issuer.ID
+	}
+
+	if len(vc.CredentialSubject) > 0 && json.Unmarshal(vc.CredentialSubject, &cred.Subjects) != nil {
+		var subject map[string]interface{}
+		if err := json.Unmarshal(vc.CredentialSubject, &subject); err != nil {
+			return models.VerifiedCredential{}, fmt.Errorf(`unmarshal error of credential subject - %v [%s]`, err, string(vc.CredentialSubject))
+		}
+		cred.Subjects = []map[string]interface{}{subject}
+	}
+
+	return cred, nil
+}
+
+// enableAutoVerify stores the verification record of an exchange requested with auto verification and verifies
+// presentations which were received before the record was stored
+func (a *Agent) enableAutoVerify(presExID string, opts models.ProofRequestOptions) {
+	if !opts.AutoVerify {
+		return
+	}
+
+	if err := a.saveVerification(models.VerificationRecord{PresExID: presExID, AutoVerify: true, CallbackURL: opts.CallbackURL}); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist verification record of %s - %v`, presExID, err))
+	}
+
+	version, p := a.exchangeProtocol(presExID)
+	data, err := a.get(a.adminUrl+p.proofRecords+presExID, fmt.Sprintf(`fetched presentation exchange record %s`, presExID))
+	if err != nil {
+		a.logger.Error(fmt.Sprintf(`check state of presentation exchange %s - %v`, presExID, err))
+		return
+	}
+
+	rec, err := parseExchangeRecord(models.ExchangeTypeProof, version, data)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf(`check state of presentation exchange %s - %v`, presExID, err))
+		return
+	}
+
+	if rec.State == `presentation-received` {
+		go a.AutoVerify(presExID)
+	}
+}
+
+// Verification returns the verification record of the exchange
+func (a *Agent) Verification(presExID string) (models.VerificationRecord, bool) {
+	a.verification.mu.Lock()
+	defer a.verification.mu.Unlock()
+
+	rec, ok := a.verification.records[presExID]
+	return rec, ok
+}
+
+// recordVerification stores the outcome of verifying the exchange in its verification record and returns the record
+func (a *Agent) recordVerification(presExID string, res models.VerificationResult, verifyErr error) models.VerificationRecord {
+	rec, _ := a.Verification(presExID)
+	now := time.Now().UTC()
+	rec.PresExID, rec.VerifiedAt, rec.Result, rec.Error = presExID, &now, nil, ``
+	if verifyErr != nil {
+		rec.Error = verifyErr.Error()
+	} else {
+		rec.Result = &res
+	}
+
+	if err := a.saveVerification(rec); err != nil {
+		a.logger.Error(fmt.Sprintf(`persist verification record of %s - %v`, presExID, err))
+	}
+
+	return rec
+}
+
+// saveVerification updates the verification record and persists all records, where the record is kept in memory
+// even if it could not be persisted
+func (a *Agent) saveVerification(rec models.VerificationRecord) error {
+	a.verification.mu.Lock()
+	defer a.verification.mu.Unlock()
+
+	a.verification.records[rec.PresExID] = rec
+	return a.verification.store.Save(a.verification.records)
+}
+
+// notifyVerification posts the verification record to its callback URL where any successful status is accepted
+func (a *Agent) notifyVerification(rec models.VerificationRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	res, err := a.verification.client.Post(rec.CallbackURL, `application/json`, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf(`transport error - %v`, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf(`response error - %d`, res.StatusCode)
+	}
+
+	a.logger.Debug(fmt.Sprintf(`verification of %s posted to %s`, rec.PresExID, rec.CallbackURL))
+	return nil
+}
+
+func (a *Agent) verifyPresentation(presExID string) (models.VerificationResult, error) {
+	version, p := a.exchangeProtocol(presExID)
+	res, err := a.post(a.adminUrl+p.proofRecords+presExID+`/verify-presentation`, nil, fmt.Sprintf(`verified presentation proof %s`, presExID))
+	if err != nil {
+		return models.VerificationResult{}, err
+	}
+
+	return parseVerification(version, res)
+}
+
+// validateProofRequestOptions accepts callback URLs only if they are absolute http(s) URLs of a host in the callback
+// allow-list, where the admin API of the agent is never accepted
+func (a *Agent) validateProofRequestOptions(opts models.ProofRequestOptions) error {
+	if opts.CallbackURL == `` {
+		return nil
+	}
+
+	if !opts.AutoVerify {
+		return fmt.Errorf(`callback URL requires auto verification`)
+	}
+
+	u, err := url.ParseRequestURI(opts.CallbackURL)
+	if err != nil {
+		return fmt.Errorf(`callback URL - %v`, err)
+	}
+
+	if (u.Scheme != `http` && u.Scheme != `https`) || u.Host == `` {
+		return fmt.Errorf(`callback URL %s is not an absolute http(s) URL`, opts.CallbackURL)
+	}
+
+	if admin, err := url.Parse(a.adminUrl); err == nil && strings.EqualFold(admin.Host, u.Host) {
+		return fmt.Errorf(`callback URL %s refers to the admin API of the agent`, opts.CallbackURL)
+	}
+
+	for _, host := range a.verification.callbackHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`host of callback URL %s is not allowed`, opts.CallbackURL)
+}
+
+// parseVerification decodes the verified exchange record of the protocol version into the verification result
+func parseVerification(version string, data []byte) (models.VerificationResult, error) {
+	var rec responses.VerifiedPresentation
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return models.VerificationResult{}, fmt.Errorf(`unmarshal error - %v [%s]`, err, string(data))
+	}
+
+	req, proof := rec.ByFormat.PresRequest.Indy, rec.ByFormat.Pres.Indy
+	result := models.VerificationResult{PresExID: rec.PresExID, Format: formatIndy, Verified: rec.Verified == `true`, VerifiedMsgs: rec.VerifiedMsgs}
+	if version == ProtocolV1 {
+		req, proof, result.PresExID = rec.PresentationRequest, rec.Presentation, rec.PresentationExchangeID
+	}
+	result.State = NormalizeState(version, rec.State)
+
+	if version != ProtocolV1 && rec.ByFormat.Pres.Dif != nil {
+		return parseDIFVerification(result, *rec.ByFormat.Pres.Dif)
+	}
+
+	source := func(i int) models.CredentialSource {
+		if i < 0 || i >= len(proof.Identifiers) {
+			return models.CredentialSource{}
+		}
+
+		id := proof.Identifiers[i]
+		return models.CredentialSource{CredDefID: id.CredDefID, SchemaID: id.SchemaID, IssuerDid: strings.SplitN(id.CredDefID, `:`, 2)[0], RevRegID: id.RevRegID}
+	}
+
+	rp := proof.RequestedProof
+	result.Attributes = []models.VerifiedAttribute{}
+	for ref, attr := range rp.RevealedAttrs {
+		result.Attributes = append(result.Attributes, models.VerifiedAttribute{Referent: ref, Revealed: true,
+			Values: map[string]string{req.RequestedAttributes[ref].Name: attr.Raw}, Source: source(attr.SubProofIndex)})
+	}
+
+	for ref, group := range rp.RevealedAttrGroups {
+		values := make(map[string]string)
+		for name, val := range group.Values {
+			values[name] = val.Raw
+		}
+		result.Attributes = append(result.Attributes, models.VerifiedAttribute{Referent: ref, Revealed: true, Values: values, Source: source(group.SubProofIndex)})
+	}
+
+	for ref, sub := range rp.UnrevealedAttrs {
+		result.Attributes = append(result.Attributes, models.VerifiedAttribute{Referent: ref, Values: map[string]string{}, Source: source(sub.SubProofIndex)})
+	}
+	sort.Slice(result.Attributes, func(i, j int) bool { return result.Attributes[i].Referent < result.Attributes[j].Referent })
+
+	// predicates are only known to hold if the proof containing them is verified
+	result.Predicates = []models.VerifiedPredicate{}
+	for ref, pred := range req.RequestedPredicates {
+		sub, ok := rp.Predicates[ref]
+		vp := models.VerifiedPredicate{Referent: ref, Name: pred.Name, PType: pred.PType, PValue: pred.PValue, Satisfied: ok && result.Verified}
+		if ok {
+			vp.Source = source(sub.SubProofIndex)
+		}
+		result.Predicates = append(result.Predicates, vp)
+	}
+	sort.Slice(result.Predicates, func(i, j int) bool { return result.Predicates[i].Referent < result.Predicates[j].Referent })
+
+	result.SelfAttested = []models.SelfAttestedAttribute{}
+	for ref, val := range rp.SelfAttestedAttrs {
+		result.SelfAttested = append(result.SelfAttested, models.SelfAttestedAttribute{Referent: ref, Name: req.RequestedAttributes[ref].Name, Value: val})
+	}
+	sort.Slice(result.SelfAttested, func(i, j int) bool { return result.SelfAttested[i].Referent < result.SelfAttested[j].Referent })
+
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/YasiruR/agent/agent/models"
+)
+
+const (
+	testIndyRequest = `{"name": "graduate", "version": "1.0",
+		"requested_attributes": {"degree": {"name": "degree"}, "name": {"name": "name"}, "email": {"name": "email"},
+			"address": {"names": ["city", "street"]}},
+		"requested_predicates": {"gpa": {"name": "gpa", "p_type": ">=", "p_value": 3}, "age": {"name": "age", "p_type": ">=", "p_value": 18}}}`
+	testIndyProof = `{"requested_proof": {
+			"revealed_attrs": {"degree": {"sub_proof_index": 0, "raw": "MSc"}},
+			"revealed_attr_groups": {"address": {"sub_proof_index": 1, "values": {"city": {"raw": "Oslo"}, "street": {"raw": "Main"}}}},
+			"unrevealed_attrs": {"name": {"sub_proof_index": 0}},
+			"self_attested_attrs": {"email": "alice@example.com"},
+			"predicates": {"gpa": {"sub_proof_index": 0}}},
+		"identifiers": [
+			{"schema_id": "WgWxqztrNooG92RXvxSTWv:2:degree:1.0", "cred_def_id": "Th7MpTaRZVRYnPiabds81Y:3:CL:12:default"},
+			{"schema_id": "WgWxqztrNooG92RXvxSTWv:2:address:1.0", "cred_def_id": "Th7MpTaRZVRYnPiabds81Y:3:CL:13:default"}]}`
+)
+
+func TestParseVerification(t *testing.T) {
+	degree := models.CredentialSource{CredDefID: `Th7MpTaRZVRYnPiabds81Y:3:CL:12:default`, SchemaID: `WgWxqztrNooG92RXvxSTWv:2:degree:1.0`, IssuerDid: `Th7MpTaRZVRYnPiabds81Y`}
+	address := models.CredentialSource{CredDefID: `Th7MpTaRZVRYnPiabds81Y:3:CL:13:default`, SchemaID: `WgWxqztrNooG92RXvxSTWv:2:address:1.0`, IssuerDid: `Th7MpTaRZVRYnPiabds81Y`}
+	indy := func(id, state string, verified bool) models.VerificationResult {
+		return models.VerificationResult{
+			PresExID: id,
+			State:    state,
+			Format:   formatIndy,
+			Verified: verified,
+			Attributes: []models.VerifiedAttribute{
+				{Referent: `address`, Revealed: true, Values: map[string]string{`city`: `Oslo`, `street`: `Main`}, Source: address},
+				{Referent: `degree`, Revealed: true, Values: map[string]string{`degree`: `MSc`}, Source: degree},
+				{Referent: `name`, Values: map[string]string{}, Source: degree},
+			},
+			Predicates: []models.VerifiedPredicate{
+				{Referent: `age`, Name: `age`, PType: `>=`, PValue: 18},
+				{Referent: `gpa`, Name: `gpa`, PType: `>=`, PValue: 3, Satisfied: verified, Source: degree},
+			},
+			SelfAttested: []models.SelfAttestedAttribute{{Referent: `email`, Name: `email`, Value: `alice@example.com`}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		data    string
+		want    models.VerificationResult
+		wantErr bool
+	}{
+		{
+			name:    `v2.0 indy`,
+			version: ProtocolV2,
+			data: `{"pres_ex_id": "pres-1", "state": "done", "verified": "true",
+				"by_format": {"pres_request": {"indy": ` + testIndyRequest + `}, "pres": {"indy": ` + testIndyProof + `}}}`,
+			want: indy(`pres-1`, `done`, true),
+		},
+		{
+			name:    `v1.0 indy`,
+			version: ProtocolV1,
+			data: `{"presentation_exchange_id": "pres-2", "state": "verified", "verified": "true",
+				"presentation_request": ` + testIndyRequest + `, "presentation": ` + testIndyProof + `}`,
+			want: indy(`pres-2`, `done`, true),
+		},
+		{
+			name:    `unverified predicates are not satisfied`,
+			version: ProtocolV2,
+			data: `{"pres_ex_id": "pres-3", "state": "done", "verified": "false",
+				"by_format": {"pres_request": {"indy": ` + testIndyRequest + `}, "pres": {"indy": ` + testIndyProof + `}}}`,
+			want: indy(`pres-3`, `done`, false),
+		},
+		{
+			name:    `v2.0 dif`,
+			version: ProtocolV2,
+			data: `{"pres_ex_id": "pres-4", "state": "done", "verified": "true", "by_format": {"pres": {"dif": {
+				"verifiableCredential": [
+					{"id": "urn:uuid:1", "type": ["VerifiableCredential"], "issuer": "did:key:z6Mk1", "credentialSubject": {"degree": "MSc"}},
+					{"type": ["VerifiableCredential", "Address"], "issuer": {"id": "did:key:z6Mk2"}, "credentialSubject": [{"city": "Oslo"}]}],
+				"presentation_submission": {"definition_id": "graduate", "descriptor_map": [
+					{"id": "residence", "format": "ldp_vc", "path": "$.verifiableCredential[1]"},
+					{"id": "degree", "format": "ldp_vc", "path": "$.verifiableCredential[0]"}]}}}}}`,
+			want: models.VerificationResult{
+				PresExID:     `pres-4`,
+				State:        `done`,
+				Format:       formatDIF,
+				Verified:     true,
+				Attributes:   []models.VerifiedAttribute{},
+				Predicates:   []models.VerifiedPredicate{},
+				SelfAttested: []models.SelfAttestedAttribute{},
+				Descriptors: []models.VerifiedDescriptor{
+					{ID: `degree`, Format: `ldp_vc`, Credential: models.VerifiedCredential{ID: `urn:uuid:1`, Types: []string{`VerifiableCredential`},
+						Issuer: `did:key:z6Mk1`, Subjects: []map[string]interface{}{{`degree`: `MSc`}}}},
+					{ID: `residence`, Format: `ldp_vc`, Credential: models.VerifiedCredential{Types: []string{`VerifiableCredential`, `Address`},
+						Issuer: `did:key:z6Mk2`, Subjects: []map[string]interface{}{{`city`: `Oslo`}}}},
+				},
+			},
+		},
+		{
+			name:    `dif submission path out of range`,
+			version: ProtocolV2,
+			data: `{"pres_ex_id": "pres-5", "state": "done", "verified": "true", "by_format": {"pres": {"dif": {
+				"verifiableCredential": [], "presentation_submission": {"descriptor_map": [{"id": "degree", "path": "$.verifiableCredential[0]"}]}}}}}`,
+			wantErr: true,
+		},
+		{
+			name:    `unsupported dif submission path`,
+			version: ProtocolV2,
+			data: `{"pres_ex_id": "pres-6", "state": "done", "verified": "true", "by_format": {"pres": {"dif": {
+				"verifiableCredential": [], "presentation_submission": {"descriptor_map": [{"id": "degree", "path": "$.credentials[0]"}]}}}}}`,
+			wantErr: true,
+		},
+		{
+			name:    `invalid record`,
+			version: ProtocolV2,
+			data:    `[]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseVerification(tc.version, []byte(tc.data))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf(`expected an error but got %+v`, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf(`parse verification - %v`, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v,\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
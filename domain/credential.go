@@ -1,10 +1,101 @@
 package domain
 
+import "fmt"
+
+const credentialPreviewType = `issue-credential/2.0/credential-preview`
+
 type CredentialPreview struct {
-	Type       string `json:"@type"`
-	Attributes []struct {
-		Mime_type string `json:"mime-type"`
-		Name      string `json:"name"`
-		Value     string `json:"value"`
-	} `json:"attributes"`
+	Type       string                `json:"@type"`
+	Attributes []CredentialAttribute `json:"attributes"`
+}
+
+type CredentialAttribute struct {
+	Mime_type string `json:"mime-type"`
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+}
+
+// OfferTemplate holds everything needed to construct a credential offer except for the attribute values, where a
+// schema family may be given instead of a filter to offer its latest version
+type OfferTemplate struct {
+	Name         string            `json:"name"`
+	Filter       IndySchemaMeta    `json:"filter"`
+	SchemaFamily string            `json:"schema_family,omitempty"`
+	PreviewType  string            `json:"preview_type"`
+	Attributes   []string          `json:"attributes"`
+	Defaults     map[string]string `json:"defaults"`
+	Comment      string            `json:"comment"`
+}
+
+// Preview builds the credential preview of the template where given values take precedence over the defaults
+func (t OfferTemplate) Preview(values map[string]string) (CredentialPreview, error) {
+	known := make(map[string]bool)
+	for _, attr := range t.Attributes {
+		known[attr] = true
+	}
+
+	for name := range values {
+		if !known[name] {
+			return CredentialPreview{}, fmt.Errorf(`attribute %s is not defined in template %s`, name, t.Name)
+		}
+	}
+
+	cp := CredentialPreview{Type: t.PreviewType}
+	if cp.Type == `` {
+		cp.Type = credentialPreviewType
+	}
+
+	for _, attr := range t.Attributes {
+		val, ok := values[attr]
+		if !ok {
+			val, ok = t.Defaults[attr]
+		}
+		if !ok {
+			return CredentialPreview{}, fmt.Errorf(`no value provided for attribute %s of template %s`, attr, t.Name)
+		}
+		cp.Attributes = append(cp.Attributes, CredentialAttribute{Name: attr, Value: val})
+	}
+
+	return cp, nil
+}
+
+// Validate checks if the template is complete enough to construct offers
+func (t OfferTemplate) Validate() error {
+	if t.Name == `` {
+		return fmt.Errorf(`template name is empty`)
+	}
+
+	if t.SchemaFamily != `` {
+		if t.Filter.CredDefID != `` {
+			return fmt.Errorf(`template %s should refer to either a schema family or a credential definition`, t.Name)
+		}
+		// attributes and defaults are checked against the latest version of the family when offering
+		if len(t.Attributes) == 0 {
+			return nil
+		}
+	} else {
+		if t.Filter.CredDefID == `` {
+			return fmt.Errorf(`credential definition ID of template %s is empty`, t.Name)
+		}
+
+		if len(t.Attributes) == 0 {
+			return fmt.Errorf(`template %s does not define any attributes`, t.Name)
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, attr := range t.Attributes {
+		if known[attr] {
+			return fmt.Errorf(`attribute %s is duplicated in template %s`, attr, t.Name)
+		}
+		known[attr] = true
+	}
+
+	for attr := range t.Defaults {
+		if !known[attr] {
+			return fmt.Errorf(`default value provided for unknown attribute %s in template %s`, attr, t.Name)
+		}
+	}
+
+	return nil
 }
@@ -1,26 +1,710 @@
 package domain
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// attribute value restrictions are encoded as attr::<name>::value and attr::<name>::marker
+const (
+	attrPrefix       = `attr::`
+	attrValueSuffix  = `::value`
+	attrMarkerSuffix = `::marker`
+)
+
+// templateParam matches the placeholders of parameters in proof request templates
+var templateParam = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
+// selection strategies of credentials when several of them match a referent
+const (
+	SelectionFirst  = `first`
+	SelectionNewest = `newest`
+)
+
+// Presentation holds the inputs of the holder to a presentation such as self-attested values, credentials chosen
+// by referent and the referents which should not be revealed
+type Presentation struct {
+	SelfAttested map[string]string `json:"self_attested_attributes"`
+	Choices      map[string]string `json:"choices"`
+	Selection    *SelectionPolicy  `json:"selection,omitempty"`
+	Unrevealed   []string          `json:"unrevealed"`
+}
+
+// SelectionPolicy decides which credential is used among those matching a referent, preferring the given issuers
+// and then ordering by the strategy
+type SelectionPolicy struct {
+	Strategy         string   `json:"strategy"`
+	PreferredIssuers []string `json:"preferred_issuers"`
+	NonRevokedOnly   bool     `json:"non_revoked_only"`
+}
+
+func (p SelectionPolicy) Validate() error {
+	switch p.Strategy {
+	case ``, SelectionFirst, SelectionNewest:
+		return nil
+	default:
+		return fmt.Errorf(`unsupported selection strategy %s`, p.Strategy)
+	}
+}
+
+// Ordered checks if candidates should be ranked instead of using the first matching one
+func (p SelectionPolicy) Ordered() bool {
+	return p.Strategy == SelectionNewest || len(p.PreferredIssuers) > 0
+}
+
+// SelfAttestedValue returns the value given for the requested attribute where the referent takes precedence. Groups
+// of attributes can not be self-attested.
+func (p Presentation) SelfAttestedValue(referent string, attr Attribute) (string, bool) {
+	if len(attr.Names) > 0 {
+		return ``, false
+	}
+
+	if val, ok := p.SelfAttested[referent]; ok {
+		return val, true
+	}
+
+	val, ok := p.SelfAttested[attr.Name]
+	return val, ok
+}
+
+// PresentationRequest holds the request in exactly one of the formats, where indy requests AnonCreds proofs and dif
+// requests W3C JSON-LD credentials with DIF presentation exchange
 type PresentationRequest struct {
-	Indy struct {
-		Name                string               `json:"name"`
-		RequestedAttributes map[string]Attribute `json:"requested_attributes"`
-		RequestedPredicates map[string]Predicate `json:"requested_predicates"`
-		Version             string               `json:"version"`
-	} `json:"indy"`
+	Indy *IndyProofRequest `json:"indy,omitempty"`
+	Dif  *DIFProofRequest  `json:"dif,omitempty"`
+}
+
+// Validate checks if the request is given in exactly one format which is valid
+func (p PresentationRequest) Validate() error {
+	if (p.Indy == nil) == (p.Dif == nil) {
+		return fmt.Errorf(`either indy or dif format should be provided`)
+	}
+
+	if p.Dif != nil {
+		return p.Dif.Validate()
+	}
+
+	for ref, attr := range p.Indy.RequestedAttributes {
+		if err := attr.Validate(); err != nil {
+			return fmt.Errorf(`invalid requested attribute %s - %v`, ref, err)
+		}
+	}
+
+	for ref, pred := range p.Indy.RequestedPredicates {
+		if err := pred.Validate(); err != nil {
+			return fmt.Errorf(`invalid requested predicate %s - %v`, ref, err)
+		}
+	}
+
+	return nil
+}
+
+// DIFProofRequest requests a verifiable presentation of W3C credentials satisfying the presentation definition
+type DIFProofRequest struct {
+	Options                *DIFOptions            `json:"options,omitempty"`
+	PresentationDefinition PresentationDefinition `json:"presentation_definition"`
+}
+
+// DIFOptions binds the presentation to the verifier, where ACA-Py generates the challenge if it is not given
+type DIFOptions struct {
+	Challenge string `json:"challenge,omitempty"`
+	Domain    string `json:"domain,omitempty"`
+}
+
+type PresentationDefinition struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name,omitempty"`
+	Purpose          string            `json:"purpose,omitempty"`
+	Format           json.RawMessage   `json:"format,omitempty"`
+	InputDescriptors []InputDescriptor `json:"input_descriptors"`
+}
+
+// InputDescriptor describes a credential of the presentation by its schemas and the constraints on its fields
+type InputDescriptor struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name,omitempty"`
+	Purpose     string            `json:"purpose,omitempty"`
+	Schema      DescriptorSchemas `json:"schema"`
+	Constraints *Constraints      `json:"constraints,omitempty"`
+}
+
+type DescriptorSchema struct {
+	URI      string `json:"uri"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// DescriptorSchemas are the schemas of an input descriptor given either as a list, which a credential should match
+// as a whole, or as an object whose oneof_filter lists groups of schemas where a credential should match any group
+type DescriptorSchemas struct {
+	OneOf  bool
+	Groups [][]DescriptorSchema
+}
+
+func (d *DescriptorSchemas) UnmarshalJSON(data []byte) error {
+	var list []DescriptorSchema
+	if err := json.Unmarshal(data, &list); err == nil {
+		d.OneOf, d.Groups = false, nil
+		if len(list) > 0 {
+			d.Groups = [][]DescriptorSchema{list}
+		}
+		return nil
+	}
+
+	var filter struct {
+		OneOfFilter json.RawMessage      `json:"oneof_filter"`
+		URIGroups   [][]DescriptorSchema `json:"uri_groups"`
+	}
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return fmt.Errorf(`schema should be a list or an object with oneof_filter - %v`, err)
+	}
+
+	// ACA-Py serializes the groups under uri_groups with a boolean oneof_filter
+	var oneOf bool
+	if err := json.Unmarshal(filter.OneOfFilter, &oneOf); err == nil {
+		d.OneOf, d.Groups = oneOf, filter.URIGroups
+		return nil
+	}
+
+	if err := json.Unmarshal(filter.OneOfFilter, &d.Groups); err != nil {
+		return fmt.Errorf(`oneof_filter should list groups of schemas - %v`, err)
+	}
+	d.OneOf = true
+
+	return nil
+}
+
+func (d DescriptorSchemas) MarshalJSON() ([]byte, error) {
+	if d.OneOf {
+		return json.Marshal(map[string][][]DescriptorSchema{`oneof_filter`: d.Groups})
+	}
+
+	var list []DescriptorSchema
+	for _, g := range d.Groups {
+		list = append(list, g...)
+	}
+
+	if list == nil {
+		list = []DescriptorSchema{}
+	}
+	return json.Marshal(list)
+}
+
+// MatchedBy checks if the schema IDs of a credential contain all schemas of the list, or of any group if given
+// by oneof_filter. Descriptors without schemas are matched by any credential.
+func (d DescriptorSchemas) MatchedBy(schemaIDs []string) bool {
+	ids := make(map[string]bool)
+	for _, id := range schemaIDs {
+		ids[id] = true
+	}
+
+	matches := func(group []DescriptorSchema) bool {
+		for _, s := range group {
+			if !ids[s.URI] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, g := range d.Groups {
+		if d.OneOf && matches(g) {
+			return true
+		}
+		if !d.OneOf && !matches(g) {
+			return false
+		}
+	}
+
+	return !d.OneOf || len(d.Groups) == 0
+}
+
+// Constraints on the fields of a credential, where limit disclosure (required or preferred) presents only the
+// constrained fields with a selective disclosure proof
+type Constraints struct {
+	LimitDisclosure string            `json:"limit_disclosure,omitempty"`
+	Fields          []ConstraintField `json:"fields,omitempty"`
+}
+
+// ConstraintField selects a field by JSON paths and filters its value with a JSON schema, where a required predicate
+// proves the filter without disclosing the value
+type ConstraintField struct {
+	ID        string          `json:"id,omitempty"`
+	Path      []string        `json:"path"`
+	Purpose   string          `json:"purpose,omitempty"`
+	Filter    json.RawMessage `json:"filter,omitempty"`
+	Predicate string          `json:"predicate,omitempty"`
+}
+
+// Validate checks if the presentation definition is complete with unique input descriptors
+func (r DIFProofRequest) Validate() error {
+	pd := r.PresentationDefinition
+	if pd.ID == `` {
+		return fmt.Errorf(`presentation definition ID is empty`)
+	}
+
+	if len(pd.InputDescriptors) == 0 {
+		return fmt.Errorf(`presentation definition %s does not define any input descriptors`, pd.ID)
+	}
+
+	known := make(map[string]bool)
+	for _, d := range pd.InputDescriptors {
+		if d.ID == `` {
+			return fmt.Errorf(`input descriptor ID is empty`)
+		}
+
+		if known[d.ID] {
+			return fmt.Errorf(`input descriptor %s is duplicated`, d.ID)
+		}
+		known[d.ID] = true
+
+		if len(d.Schema.Groups) == 0 {
+			return fmt.Errorf(`input descriptor %s does not define any schema`, d.ID)
+		}
+
+		for _, g := range d.Schema.Groups {
+			if len(g) == 0 {
+				return fmt.Errorf(`input descriptor %s has an empty group of schemas`, d.ID)
+			}
+
+			for _, sc := range g {
+				if sc.URI == `` {
+					return fmt.Errorf(`schema URI of input descriptor %s is empty`, d.ID)
+				}
+			}
+		}
+
+		if d.Constraints == nil {
+			continue
+		}
+
+		if !validDisclosure(d.Constraints.LimitDisclosure) {
+			return fmt.Errorf(`unsupported limit disclosure %s in input descriptor %s`, d.Constraints.LimitDisclosure, d.ID)
+		}
+
+		for _, f := range d.Constraints.Fields {
+			if len(f.Path) == 0 {
+				return fmt.Errorf(`field of input descriptor %s does not define any path`, d.ID)
+			}
+
+			if !validDisclosure(f.Predicate) {
+				return fmt.Errorf(`unsupported predicate %s in input descriptor %s`, f.Predicate, d.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DescriptorIDs returns the IDs of the input descriptors of the presentation definition
+func (r DIFProofRequest) DescriptorIDs() []string {
+	var ids []string
+	for _, d := range r.PresentationDefinition.InputDescriptors {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func validDisclosure(v string) bool {
+	return v == `` || v == `required` || v == `preferred`
 }
 
+type IndyProofRequest struct {
+	Name                string               `json:"name"`
+	RequestedAttributes map[string]Attribute `json:"requested_attributes"`
+	RequestedPredicates map[string]Predicate `json:"requested_predicates"`
+	Version             string               `json:"version"`
+}
+
+// Attribute is a requested attribute where SchemaFamily is resolved into a restriction on the schema name and
+// issuer before the request is sent, and Names requests a group of attributes proved by the same credential
 type Attribute struct {
-	Name         string `json:"name"`
-	Restrictions []struct {
-		CredDefID string `json:"cred_def_id"`
-	} `json:"restrictions"`
+	Name               string        `json:"name,omitempty"`
+	Names              []string      `json:"names,omitempty"`
+	Restrictions       []Restriction `json:"restrictions"`
+	SchemaFamily       string        `json:"schema_family,omitempty"`
+	SchemaFamilyIssuer string        `json:"schema_family_issuer,omitempty"`
+	AllowUnrevealed    bool          `json:"allow_unrevealed,omitempty"` // extension of this controller, not of AnonCreds
+}
+
+// AttributeNames returns the names of all attributes requested by the attribute or the group
+func (a Attribute) AttributeNames() []string {
+	if len(a.Names) > 0 {
+		return a.Names
+	}
+	return []string{a.Name}
+}
+
+// Validate checks if either a single attribute or a group of attributes is requested
+func (a Attribute) Validate() error {
+	if (a.Name == ``) == (len(a.Names) == 0) {
+		return fmt.Errorf(`either name or names should be provided`)
+	}
+
+	known := make(map[string]bool)
+	for _, name := range a.Names {
+		if name == `` {
+			return fmt.Errorf(`names should not be empty`)
+		}
+
+		if known[CanonicalAttribute(name)] {
+			return fmt.Errorf(`attribute %s is duplicated in names`, name)
+		}
+		known[CanonicalAttribute(name)] = true
+	}
+
+	return nil
 }
 
 type Predicate struct {
-	Name         string `json:"name"`
-	PType        string `json:"p_type"`
-	PValue       int64  `json:"p_value"`
-	Restrictions []struct {
-		CredDefID string `json:"cred_def_id"`
-	} `json:"restrictions"`
+	Name               string        `json:"name"`
+	PType              string        `json:"p_type"`
+	PValue             int64         `json:"p_value"`
+	Restrictions       []Restriction `json:"restrictions"`
+	SchemaFamily       string        `json:"schema_family,omitempty"`
+	SchemaFamilyIssuer string        `json:"schema_family_issuer,omitempty"`
+}
+
+// Validate checks if the predicate type is one of those supported by AnonCreds
+func (p Predicate) Validate() error {
+	switch p.PType {
+	case `>=`, `>`, `<=`, `<`:
+		return nil
+	default:
+		return fmt.Errorf(`unsupported predicate type %s`, p.PType)
+	}
+}
+
+// SatisfiedBy checks if the raw value of a credential attribute satisfies the predicate
+func (p Predicate) SatisfiedBy(value string) (bool, error) {
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf(`value of attribute %s is not an integer [%s]`, p.Name, value)
+	}
+
+	switch p.PType {
+	case `>=`:
+		return v >= p.PValue, nil
+	case `>`:
+		return v > p.PValue, nil
+	case `<=`:
+		return v <= p.PValue, nil
+	case `<`:
+		return v < p.PValue, nil
+	default:
+		return false, fmt.Errorf(`unsupported predicate type %s`, p.PType)
+	}
+}
+
+// Restriction is a set of conditions which should all hold for a credential, where attribute conditions are
+// encoded as attr::<name>::value and attr::<name>::marker in JSON
+type Restriction struct {
+	SchemaID        string            `json:"schema_id,omitempty"`
+	SchemaIssuerDid string            `json:"schema_issuer_did,omitempty"`
+	SchemaName      string            `json:"schema_name,omitempty"`
+	SchemaVersion   string            `json:"schema_version,omitempty"`
+	IssuerDid       string            `json:"issuer_did,omitempty"`
+	CredDefID       string            `json:"cred_def_id,omitempty"`
+	RevRegID        string            `json:"rev_reg_id,omitempty"`
+	AttrValues      map[string]string `json:"-"`
+	AttrMarkers     []string          `json:"-"`
+}
+
+func (r Restriction) MarshalJSON() ([]byte, error) {
+	m := make(map[string]string)
+	for key, val := range r.fields() {
+		if *val != `` {
+			m[key] = *val
+		}
+	}
+
+	for name, val := range r.AttrValues {
+		m[attrPrefix+name+attrValueSuffix] = val
+	}
+
+	// the value of a marker is always 1 as only the presence of the attribute is checked
+	for _, name := range r.AttrMarkers {
+		m[attrPrefix+name+attrMarkerSuffix] = `1`
+	}
+
+	return json.Marshal(m)
+}
+
+func (r *Restriction) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	*r = Restriction{}
+	fields := r.fields()
+	for key, val := range m {
+		if field, ok := fields[key]; ok {
+			*field = val
+			continue
+		}
+
+		if !strings.HasPrefix(key, attrPrefix) {
+			return fmt.Errorf(`unsupported restriction %s`, key)
+		}
+
+		switch name := strings.TrimPrefix(key, attrPrefix); {
+		case strings.HasSuffix(name, attrValueSuffix) && len(name) > len(attrValueSuffix):
+			if r.AttrValues == nil {
+				r.AttrValues = make(map[string]string)
+			}
+			r.AttrValues[strings.TrimSuffix(name, attrValueSuffix)] = val
+		case strings.HasSuffix(name, attrMarkerSuffix) && len(name) > len(attrMarkerSuffix):
+			r.AttrMarkers = append(r.AttrMarkers, strings.TrimSuffix(name, attrMarkerSuffix))
+		default:
+			return fmt.Errorf(`unsupported restriction %s`, key)
+		}
+	}
+
+	sort.Strings(r.AttrMarkers)
+	return nil
+}
+
+// Matches checks if a credential with the given identifiers and raw attribute values meets all conditions of the
+// restriction, where schema and issuer details are derived from the identifiers
+func (r Restriction) Matches(schemaID, credDefID, revRegID string, attrs map[string]string) bool {
+	// schema IDs are in the form <issuer DID>:2:<name>:<version> and credential definition IDs start with the issuer DID
+	schemaParts := strings.Split(schemaID, `:`)
+	if len(schemaParts) != 4 {
+		schemaParts = []string{``, ``, ``, ``}
+	}
+
+	conditions := [][2]string{
+		{r.SchemaID, schemaID},
+		{r.SchemaIssuerDid, schemaParts[0]},
+		{r.SchemaName, schemaParts[2]},
+		{r.SchemaVersion, schemaParts[3]},
+		{r.IssuerDid, strings.SplitN(credDefID, `:`, 2)[0]},
+		{r.CredDefID, credDefID},
+		{r.RevRegID, revRegID},
+	}
+	for _, c := range conditions {
+		if c[0] != `` && c[0] != c[1] {
+			return false
+		}
+	}
+
+	values := make(map[string]string)
+	for name, val := range attrs {
+		values[CanonicalAttribute(name)] = val
+	}
+
+	for name, want := range r.AttrValues {
+		if val, ok := values[CanonicalAttribute(name)]; !ok || val != want {
+			return false
+		}
+	}
+
+	for _, name := range r.AttrMarkers {
+		if _, ok := values[CanonicalAttribute(name)]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Restriction) fields() map[string]*string {
+	return map[string]*string{
+		`schema_id`:         &r.SchemaID,
+		`schema_issuer_did`: &r.SchemaIssuerDid,
+		`schema_name`:       &r.SchemaName,
+		`schema_version`:    &r.SchemaVersion,
+		`issuer_did`:        &r.IssuerDid,
+		`cred_def_id`:       &r.CredDefID,
+		`rev_reg_id`:        &r.RevRegID,
+	}
+}
+
+// MatchesAny checks if the credential meets any of the restrictions, where no restrictions accept any credential
+func MatchesAny(restrictions []Restriction, schemaID, credDefID, revRegID string, attrs map[string]string) bool {
+	if len(restrictions) == 0 {
+		return true
+	}
+
+	for _, r := range restrictions {
+		if r.Matches(schemaID, credDefID, revRegID, attrs) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProofTemplate is a named presentation request whose values may contain ${param} placeholders of the parameters,
+// where parameters without a default value (null) must be given when sending the request
+type ProofTemplate struct {
+	Name       string                 `json:"name"`
+	Request    json.RawMessage        `json:"presentation_request"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// Validate checks if the request is a JSON object using only the declared parameters, and that it is a valid
+// presentation request when all parameters have defaults
+func (t ProofTemplate) Validate() error {
+	if t.Name == `` {
+		return fmt.Errorf(`template name is empty`)
+	}
+
+	req, err := t.decodeRequest()
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]bool)
+	if _, err = substituteParams(req, func(name string) (interface{}, error) {
+		if _, ok := t.Parameters[name]; !ok {
+			return nil, fmt.Errorf(`parameter %s is not declared in template %s`, name, t.Name)
+		}
+		used[name] = true
+		return ``, nil
+	}); err != nil {
+		return err
+	}
+
+	var required bool
+	for name, def := range t.Parameters {
+		if !used[name] {
+			return fmt.Errorf(`parameter %s is not used in template %s`, name, t.Name)
+		}
+		required = required || def == nil
+	}
+
+	// requests with required parameters can only be checked once the values are given
+	if !required {
+		if _, err = t.Render(nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Render builds the presentation request of the template where the given values override the defaults
+func (t ProofTemplate) Render(values map[string]interface{}) (PresentationRequest, error) {
+	for name := range values {
+		if _, ok := t.Parameters[name]; !ok {
+			return PresentationRequest{}, fmt.Errorf(`parameter %s is not defined in template %s`, name, t.Name)
+		}
+	}
+
+	req, err := t.decodeRequest()
+	if err != nil {
+		return PresentationRequest{}, err
+	}
+
+	rendered, err := substituteParams(req, func(name string) (interface{}, error) {
+		if val, ok := values[name]; ok && val != nil {
+			return val, nil
+		}
+
+		if def := t.Parameters[name]; def != nil {
+			return def, nil
+		}
+
+		return nil, fmt.Errorf(`no value provided for parameter %s of template %s`, name, t.Name)
+	})
+	if err != nil {
+		return PresentationRequest{}, err
+	}
+
+	data, err := json.Marshal(rendered)
+	if err != nil {
+		return PresentationRequest{}, fmt.Errorf(`marshal error - %v`, err)
+	}
+
+	var pr PresentationRequest
+	if err = json.Unmarshal(data, &pr); err != nil {
+		return PresentationRequest{}, fmt.Errorf(`invalid presentation request of template %s - %v`, t.Name, err)
+	}
+
+	if err = pr.Validate(); err != nil {
+		return PresentationRequest{}, fmt.Errorf(`invalid presentation request of template %s - %v`, t.Name, err)
+	}
+
+	return pr, nil
+}
+
+func (t ProofTemplate) decodeRequest() (map[string]interface{}, error) {
+	var req map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(t.Request))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil || req == nil {
+		return nil, fmt.Errorf(`presentation request of template %s is not a JSON object`, t.Name)
+	}
+
+	return req, nil
+}
+
+// substituteParams replaces the placeholders in keys and values of the decoded JSON value with the parameters
+// returned by lookup
+func substituteParams(v interface{}, lookup func(name string) (interface{}, error)) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for key, elem := range val {
+			k, err := substituteText(key, lookup)
+			if err != nil {
+				return nil, err
+			}
+
+			if res[k], err = substituteParams(elem, lookup); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, elem := range val {
+			var err error
+			if res[i], err = substituteParams(elem, lookup); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case string:
+		if m := templateParam.FindStringSubmatch(val); m != nil && m[0] == val {
+			return lookup(m[1])
+		}
+		return substituteText(val, lookup)
+	default:
+		return v, nil
+	}
+}
+
+// substituteText replaces the placeholders within a string where parameters should be strings or numbers
+func substituteText(s string, lookup func(name string) (interface{}, error)) (string, error) {
+	var err error
+	res := templateParam.ReplaceAllStringFunc(s, func(match string) string {
+		if err != nil {
+			return match
+		}
+
+		var val interface{}
+		if val, err = lookup(templateParam.FindStringSubmatch(match)[1]); err != nil {
+			return match
+		}
+
+		switch v := val.(type) {
+		case float64:
+			// values decoded from JSON requests would otherwise be formatted in exponent notation if large
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case string, json.Number, int, int64, bool:
+			return fmt.Sprint(v)
+		default:
+			err = fmt.Errorf(`parameter in %s should be a string or a number`, s)
+			return match
+		}
+	})
+
+	return res, err
 }
@@ -0,0 +1,406 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/YasiruR/agent/domain"
+)
+
+const (
+	testSchemaID  = `WgWxqztrNooG92RXvxSTWv:2:degree:1.0`
+	testCredDefID = `Th7MpTaRZVRYnPiabds81Y:3:CL:12:default`
+	testRevRegID  = `Th7MpTaRZVRYnPiabds81Y:4:Th7MpTaRZVRYnPiabds81Y:3:CL:12:default:CL_ACCUM:0`
+)
+
+func TestRestrictionMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		r    domain.Restriction
+		want map[string]string
+	}{
+		{
+			name: `empty`,
+			r:    domain.Restriction{},
+			want: map[string]string{},
+		},
+		{
+			name: `identifiers only`,
+			r:    domain.Restriction{SchemaName: `degree`, IssuerDid: `Th7MpTaRZVRYnPiabds81Y`},
+			want: map[string]string{`schema_name`: `degree`, `issuer_did`: `Th7MpTaRZVRYnPiabds81Y`},
+		},
+		{
+			name: `attribute values and markers`,
+			r:    domain.Restriction{CredDefID: testCredDefID, AttrValues: map[string]string{`degree`: `MSc`}, AttrMarkers: []string{`gpa`}},
+			want: map[string]string{`cred_def_id`: testCredDefID, `attr::degree::value`: `MSc`, `attr::gpa::marker`: `1`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.r)
+			if err != nil {
+				t.Fatalf(`marshal restriction - %v`, err)
+			}
+
+			var got map[string]string
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatalf(`unmarshal marshalled restriction - %v [%s]`, err, data)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf(`got %v, want %v`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRestrictionUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    domain.Restriction
+		wantErr bool
+	}{
+		{
+			name: `identifiers`,
+			data: `{"schema_id": "` + testSchemaID + `", "rev_reg_id": "` + testRevRegID + `"}`,
+			want: domain.Restriction{SchemaID: testSchemaID, RevRegID: testRevRegID},
+		},
+		{
+			name: `attribute values and sorted markers`,
+			data: `{"attr::degree::value": "MSc", "attr::name::marker": "1", "attr::gpa::marker": "1"}`,
+			want: domain.Restriction{AttrValues: map[string]string{`degree`: `MSc`}, AttrMarkers: []string{`gpa`, `name`}},
+		},
+		{
+			name: `attribute names with separators`,
+			data: `{"attr::first::name::value": "Alice"}`,
+			want: domain.Restriction{AttrValues: map[string]string{`first::name`: `Alice`}},
+		},
+		{
+			name:    `unknown key`,
+			data:    `{"schema_owner": "Th7MpTaRZVRYnPiabds81Y"}`,
+			wantErr: true,
+		},
+		{
+			name:    `attribute without name`,
+			data:    `{"attr::::value": "MSc"}`,
+			wantErr: true,
+		},
+		{
+			name:    `unknown attribute condition`,
+			data:    `{"attr::degree::equals": "MSc"}`,
+			wantErr: true,
+		},
+		{
+			name:    `non-string value`,
+			data:    `{"attr::gpa::marker": 1}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got domain.Restriction
+			err := json.Unmarshal([]byte(tc.data), &got)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf(`expected an error but got %+v`, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf(`unmarshal restriction - %v`, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf(`got %+v, want %+v`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRestrictionMatches(t *testing.T) {
+	attrs := map[string]string{`Degree`: `MSc`, `GPA`: `3.8`}
+	tests := []struct {
+		name string
+		r    domain.Restriction
+		want bool
+	}{
+		{name: `empty`, r: domain.Restriction{}, want: true},
+		{name: `schema ID`, r: domain.Restriction{SchemaID: testSchemaID}, want: true},
+		{name: `other schema ID`, r: domain.Restriction{SchemaID: `WgWxqztrNooG92RXvxSTWv:2:degree:2.0`}, want: false},
+		{name: `schema issuer`, r: domain.Restriction{SchemaIssuerDid: `WgWxqztrNooG92RXvxSTWv`}, want: true},
+		{name: `schema name and version`, r: domain.Restriction{SchemaName: `degree`, SchemaVersion: `1.0`}, want: true},
+		{name: `other schema version`, r: domain.Restriction{SchemaName: `degree`, SchemaVersion: `1.1`}, want: false},
+		{name: `issuer`, r: domain.Restriction{IssuerDid: `Th7MpTaRZVRYnPiabds81Y`}, want: true},
+		{name: `schema issuer as issuer`, r: domain.Restriction{IssuerDid: `WgWxqztrNooG92RXvxSTWv`}, want: false},
+		{name: `credential definition`, r: domain.Restriction{CredDefID: testCredDefID}, want: true},
+		{name: `revocation registry`, r: domain.Restriction{RevRegID: testRevRegID}, want: true},
+		{name: `attribute value in other case`, r: domain.Restriction{AttrValues: map[string]string{`degree`: `MSc`}}, want: true},
+		{name: `other attribute value`, r: domain.Restriction{AttrValues: map[string]string{`degree`: `BSc`}}, want: false},
+		{name: `attribute marker`, r: domain.Restriction{AttrMarkers: []string{`gpa`}}, want: true},
+		{name: `missing attribute marker`, r: domain.Restriction{AttrMarkers: []string{`name`}}, want: false},
+		{name: `all conditions`, r: domain.Restriction{SchemaName: `degree`, CredDefID: testCredDefID, AttrMarkers: []string{`gpa`}}, want: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.r.Matches(testSchemaID, testCredDefID, testRevRegID, attrs); got != tc.want {
+				t.Errorf(`got %t, want %t`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPresentationRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     domain.PresentationRequest
+		wantErr bool
+	}{
+		{
+			name:    `no format`,
+			req:     domain.PresentationRequest{},
+			wantErr: true,
+		},
+		{
+			name: `valid indy request`,
+			req: domain.PresentationRequest{Indy: &domain.IndyProofRequest{
+				RequestedAttributes: map[string]domain.Attribute{`degree`: {Name: `degree`}},
+				RequestedPredicates: map[string]domain.Predicate{`gpa`: {Name: `gpa`, PType: `>=`, PValue: 3}},
+			}},
+		},
+		{
+			name: `attribute with both name and names`,
+			req: domain.PresentationRequest{Indy: &domain.IndyProofRequest{
+				RequestedAttributes: map[string]domain.Attribute{`degree`: {Name: `degree`, Names: []string{`degree`}}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: `unsupported predicate type`,
+			req: domain.PresentationRequest{Indy: &domain.IndyProofRequest{
+				RequestedPredicates: map[string]domain.Predicate{`gpa`: {Name: `gpa`, PType: `==`, PValue: 3}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.req.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf(`got error %v, want error %t`, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDescriptorSchemasUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    domain.DescriptorSchemas
+		wantErr bool
+	}{
+		{
+			name: `list`,
+			data: `[{"uri": "https://example.org/A"}, {"uri": "https://example.org/B", "required": true}]`,
+			want: domain.DescriptorSchemas{Groups: [][]domain.DescriptorSchema{{{URI: `https://example.org/A`}, {URI: `https://example.org/B`, Required: true}}}},
+		},
+		{
+			name: `empty list`,
+			data: `[]`,
+			want: domain.DescriptorSchemas{},
+		},
+		{
+			name: `oneof_filter`,
+			data: `{"oneof_filter": [[{"uri": "https://example.org/A"}], [{"uri": "https://example.org/B"}]]}`,
+			want: domain.DescriptorSchemas{OneOf: true, Groups: [][]domain.DescriptorSchema{{{URI: `https://example.org/A`}}, {{URI: `https://example.org/B`}}}},
+		},
+		{
+			name: `uri_groups of ACA-Py`,
+			data: `{"oneof_filter": true, "uri_groups": [[{"uri": "https://example.org/A"}]]}`,
+			want: domain.DescriptorSchemas{OneOf: true, Groups: [][]domain.DescriptorSchema{{{URI: `https://example.org/A`}}}},
+		},
+		{
+			name:    `string`,
+			data:    `"https://example.org/A"`,
+			wantErr: true,
+		},
+		{
+			name:    `oneof_filter of schemas`,
+			data:    `{"oneof_filter": [{"uri": "https://example.org/A"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got domain.DescriptorSchemas
+			err := json.Unmarshal([]byte(tc.data), &got)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf(`expected an error but got %+v`, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf(`unmarshal schemas - %v`, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf(`got %+v, want %+v`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDescriptorSchemasMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		d    domain.DescriptorSchemas
+		want string
+	}{
+		{
+			name: `empty`,
+			d:    domain.DescriptorSchemas{},
+			want: `[]`,
+		},
+		{
+			name: `list`,
+			d:    domain.DescriptorSchemas{Groups: [][]domain.DescriptorSchema{{{URI: `https://example.org/A`}}}},
+			want: `[{"uri":"https://example.org/A"}]`,
+		},
+		{
+			name: `oneof_filter`,
+			d:    domain.DescriptorSchemas{OneOf: true, Groups: [][]domain.DescriptorSchema{{{URI: `https://example.org/A`}}, {{URI: `https://example.org/B`}}}},
+			want: `{"oneof_filter":[[{"uri":"https://example.org/A"}],[{"uri":"https://example.org/B"}]]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.d)
+			if err != nil {
+				t.Fatalf(`marshal schemas - %v`, err)
+			}
+
+			if string(data) != tc.want {
+				t.Errorf(`got %s, want %s`, data, tc.want)
+			}
+		})
+	}
+}
+
+func TestDescriptorSchemasMatchedBy(t *testing.T) {
+	a, b, c := domain.DescriptorSchema{URI: `https://example.org/A`}, domain.DescriptorSchema{URI: `https://example.org/B`}, domain.DescriptorSchema{URI: `https://example.org/C`}
+	tests := []struct {
+		name      string
+		d         domain.DescriptorSchemas
+		schemaIDs []string
+		want      bool
+	}{
+		{name: `no schemas`, d: domain.DescriptorSchemas{}, schemaIDs: []string{a.URI}, want: true},
+		{name: `list fully present`, d: domain.DescriptorSchemas{Groups: [][]domain.DescriptorSchema{{a, b}}}, schemaIDs: []string{b.URI, a.URI, c.URI}, want: true},
+		{name: `list partially present`, d: domain.DescriptorSchemas{Groups: [][]domain.DescriptorSchema{{a, b}}}, schemaIDs: []string{a.URI}, want: false},
+		{name: `oneof_filter first group`, d: domain.DescriptorSchemas{OneOf: true, Groups: [][]domain.DescriptorSchema{{a}, {b, c}}}, schemaIDs: []string{a.URI}, want: true},
+		{name: `oneof_filter second group`, d: domain.DescriptorSchemas{OneOf: true, Groups: [][]domain.DescriptorSchema{{a}, {b, c}}}, schemaIDs: []string{b.URI, c.URI}, want: true},
+		{name: `oneof_filter partial group`, d: domain.DescriptorSchemas{OneOf: true, Groups: [][]domain.DescriptorSchema{{a}, {b, c}}}, schemaIDs: []string{c.URI}, want: false},
+		{name: `no schema IDs`, d: domain.DescriptorSchemas{Groups: [][]domain.DescriptorSchema{{a}}}, schemaIDs: nil, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.d.MatchedBy(tc.schemaIDs); got != tc.want {
+				t.Errorf(`got %t, want %t`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProofTemplateRender(t *testing.T) {
+	tmpl := domain.ProofTemplate{
+		Name: `graduate`,
+		Request: json.RawMessage(`{"indy": {"name": "graduate ${year}", "version": "1.0",
+			"requested_attributes": {"degree": {"name": "degree", "restrictions": [{"cred_def_id": "${cred_def}"}]}},
+			"requested_predicates": {"gpa": {"name": "gpa", "p_type": "${p_type}", "p_value": "${min_gpa}", "restrictions": []}}}}`),
+		Parameters: map[string]interface{}{`year`: nil, `cred_def`: testCredDefID, `p_type`: `>=`, `min_gpa`: json.Number(`3`)},
+	}
+
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		wantName string
+		wantPred domain.Predicate
+		wantErr  bool
+	}{
+		{
+			name:     `defaults`,
+			values:   map[string]interface{}{`year`: `2024`},
+			wantName: `graduate 2024`,
+			wantPred: domain.Predicate{Name: `gpa`, PType: `>=`, PValue: 3, Restrictions: []domain.Restriction{}},
+		},
+		{
+			name:     `numbers decoded from requests`,
+			values:   map[string]interface{}{`year`: float64(20240101), `min_gpa`: float64(4)},
+			wantName: `graduate 20240101`,
+			wantPred: domain.Predicate{Name: `gpa`, PType: `>=`, PValue: 4, Restrictions: []domain.Restriction{}},
+		},
+		{
+			name:    `missing value`,
+			values:  map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:    `undeclared parameter`,
+			values:  map[string]interface{}{`year`: `2024`, `max_gpa`: 4},
+			wantErr: true,
+		},
+		{
+			name:    `object in text`,
+			values:  map[string]interface{}{`year`: map[string]interface{}{`from`: 2020}},
+			wantErr: true,
+		},
+		{
+			name:    `invalid rendered request`,
+			values:  map[string]interface{}{`year`: `2024`, `p_type`: `==`},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pr, err := tmpl.Render(tc.values)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf(`expected an error but got %+v`, pr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf(`render template - %v`, err)
+			}
+
+			if pr.Indy == nil {
+				t.Fatalf(`rendered request should be in indy format [%+v]`, pr)
+			}
+
+			if pr.Indy.Name != tc.wantName {
+				t.Errorf(`got name %s, want %s`, pr.Indy.Name, tc.wantName)
+			}
+
+			if got := pr.Indy.RequestedAttributes[`degree`].Restrictions; len(got) != 1 || got[0].CredDefID != testCredDefID {
+				t.Errorf(`got restrictions %+v, want the credential definition %s`, got, testCredDefID)
+			}
+
+			if got := pr.Indy.RequestedPredicates[`gpa`]; !reflect.DeepEqual(got, tc.wantPred) {
+				t.Errorf(`got predicate %+v, want %+v`, got, tc.wantPred)
+			}
+		})
+	}
+}
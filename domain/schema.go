@@ -1,5 +1,17 @@
 package domain
 
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxSchemaAttributes = 125
+
+var schemaVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+){1,2}$`)
+
 type IndySchemaMeta struct {
 	CredDefID       string `json:"cred_def_id"`
 	IssuerDid       string `json:"issuer_did"`
@@ -8,3 +20,181 @@ type IndySchemaMeta struct {
 	SchemaName      string `json:"schema_name"`
 	SchemaVersion   string `json:"schema_version"`
 }
+
+// Schema is the request to publish a schema on the ledger
+type Schema struct {
+	SchemaName    string   `json:"schema_name"`
+	SchemaVersion string   `json:"schema_version"`
+	Attributes    []string `json:"attributes"`
+}
+
+// Validate checks the schema against the constraints of the ledger so that invalid schemas are rejected before
+// reaching the agent
+func (s Schema) Validate() error {
+	if strings.TrimSpace(s.SchemaName) == `` {
+		return fmt.Errorf(`schema name is empty`)
+	}
+
+	if strings.Contains(s.SchemaName, `:`) {
+		return fmt.Errorf(`schema name %s contains ':'`, s.SchemaName)
+	}
+
+	if !schemaVersionPattern.MatchString(s.SchemaVersion) {
+		return fmt.Errorf(`schema version %s should be in the form of major.minor(.patch) with digits only`, s.SchemaVersion)
+	}
+
+	if len(s.Attributes) == 0 {
+		return fmt.Errorf(`schema %s does not define any attributes`, s.SchemaName)
+	}
+
+	if len(s.Attributes) > maxSchemaAttributes {
+		return fmt.Errorf(`schema %s defines %d attributes while the maximum is %d`, s.SchemaName, len(s.Attributes), maxSchemaAttributes)
+	}
+
+	// attribute names are compared in the canonical form (lower case without spaces) as used in credentials
+	known := make(map[string]bool)
+	for _, attr := range s.Attributes {
+		canonical := CanonicalAttribute(attr)
+		if canonical == `` {
+			return fmt.Errorf(`schema %s contains an empty attribute name`, s.SchemaName)
+		}
+
+		if known[canonical] {
+			return fmt.Errorf(`attribute %s is duplicated in schema %s`, attr, s.SchemaName)
+		}
+		known[canonical] = true
+	}
+
+	return nil
+}
+
+// LedgerSchema is a schema as published on the ledger
+type LedgerSchema struct {
+	Ver       string   `json:"ver"`
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	AttrNames []string `json:"attrNames"`
+	SeqNo     int      `json:"seqNo"`
+}
+
+// CanonicalAttribute returns the attribute name in lower case without any spaces
+func CanonicalAttribute(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), ``))
+}
+
+// CredentialDefinitionSetup describes a credential definition along with the schema it is based on. Either the ID
+// of an existing schema or the schema itself should be provided.
+type CredentialDefinitionSetup struct {
+	SchemaID               string  `json:"schema_id,omitempty"`
+	Schema                 *Schema `json:"schema,omitempty"`
+	Tag                    string  `json:"tag"`
+	SupportRevocation      bool    `json:"support_revocation"`
+	RevocationRegistrySize int     `json:"revocation_registry_size,omitempty"`
+}
+
+// SchemaEvolution describes the next version of an existing schema along with the credential definition to be
+// created for it. The version is derived by bumping the minor version of the existing schema unless provided.
+type SchemaEvolution struct {
+	SchemaID               string   `json:"schema_id"`
+	AddAttributes          []string `json:"add_attributes"`
+	RemoveAttributes       []string `json:"remove_attributes"`
+	Version                string   `json:"version,omitempty"`
+	Tag                    string   `json:"tag"`
+	SupportRevocation      bool     `json:"support_revocation"`
+	RevocationRegistrySize int      `json:"revocation_registry_size,omitempty"`
+}
+
+// Evolve applies the attribute changes to the schema and returns the schema of the next version
+func (s LedgerSchema) Evolve(e SchemaEvolution) (Schema, error) {
+	if len(e.AddAttributes) == 0 && len(e.RemoveAttributes) == 0 {
+		return Schema{}, fmt.Errorf(`no attribute changes provided for schema %s`, s.ID)
+	}
+
+	removed := make(map[string]bool)
+	for _, attr := range e.RemoveAttributes {
+		removed[CanonicalAttribute(attr)] = true
+	}
+
+	var attrs []string
+	for _, attr := range s.AttrNames {
+		if removed[CanonicalAttribute(attr)] {
+			delete(removed, CanonicalAttribute(attr))
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+
+	if len(removed) > 0 {
+		var missing []string
+		for attr := range removed {
+			missing = append(missing, attr)
+		}
+		sort.Strings(missing)
+		return Schema{}, fmt.Errorf(`attributes %v to be removed do not exist in schema %s`, missing, s.ID)
+	}
+
+	// duplicates of existing attributes are rejected by the validation of the schema
+	attrs = append(attrs, e.AddAttributes...)
+
+	version := e.Version
+	if version == `` {
+		next, err := NextSchemaVersion(s.Version)
+		if err != nil {
+			return Schema{}, err
+		}
+		version = next
+	}
+
+	if CompareSchemaVersions(version, s.Version) <= 0 {
+		return Schema{}, fmt.Errorf(`version %s should be greater than the version %s of schema %s`, version, s.Version, s.ID)
+	}
+
+	next := Schema{SchemaName: s.Name, SchemaVersion: version, Attributes: attrs}
+	if err := next.Validate(); err != nil {
+		return Schema{}, err
+	}
+
+	return next, nil
+}
+
+// NextSchemaVersion bumps the minor version (eg: 1.2 to 1.3 and 1.2.5 to 1.3.0)
+func NextSchemaVersion(version string) (string, error) {
+	if !schemaVersionPattern.MatchString(version) {
+		return ``, fmt.Errorf(`version %s should be in the form of major.minor(.patch) to be bumped`, version)
+	}
+
+	parts := strings.Split(version, `.`)
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ``, fmt.Errorf(`invalid minor version of %s - %v`, version, err)
+	}
+
+	parts[1] = strconv.Itoa(minor + 1)
+	if len(parts) == 3 {
+		parts[2] = `0`
+	}
+
+	return strings.Join(parts, `.`), nil
+}
+
+// CompareSchemaVersions compares the numeric segments of two versions and returns a negative value, zero or a
+// positive value if the first is lower than, equal to or greater than the second respectively
+func CompareSchemaVersions(v1, v2 string) int {
+	p1, p2 := strings.Split(v1, `.`), strings.Split(v2, `.`)
+	for i := 0; i < len(p1) || i < len(p2); i++ {
+		var n1, n2 int
+		if i < len(p1) {
+			n1, _ = strconv.Atoi(p1[i])
+		}
+		if i < len(p2) {
+			n2, _ = strconv.Atoi(p2[i])
+		}
+
+		if n1 != n2 {
+			return n1 - n2
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,144 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/YasiruR/agent/domain"
+)
+
+func TestLedgerSchemaEvolve(t *testing.T) {
+	schema := domain.LedgerSchema{ID: `WgWxqztrNooG92RXvxSTWv:2:degree:1.2`, Name: `degree`, Version: `1.2`, AttrNames: []string{`name`, `degree`, `Date Of Birth`}}
+	tests := []struct {
+		name    string
+		e       domain.SchemaEvolution
+		want    domain.Schema
+		wantErr bool
+	}{
+		{
+			name: `add attribute with next version`,
+			e:    domain.SchemaEvolution{AddAttributes: []string{`gpa`}},
+			want: domain.Schema{SchemaName: `degree`, SchemaVersion: `1.3`, Attributes: []string{`name`, `degree`, `Date Of Birth`, `gpa`}},
+		},
+		{
+			name: `remove attribute in canonical form`,
+			e:    domain.SchemaEvolution{RemoveAttributes: []string{`dateofbirth`}, Version: `2.0`},
+			want: domain.Schema{SchemaName: `degree`, SchemaVersion: `2.0`, Attributes: []string{`name`, `degree`}},
+		},
+		{
+			name: `replace attribute`,
+			e:    domain.SchemaEvolution{AddAttributes: []string{`birthdate`}, RemoveAttributes: []string{`Date Of Birth`}, Version: `1.2.1`},
+			want: domain.Schema{SchemaName: `degree`, SchemaVersion: `1.2.1`, Attributes: []string{`name`, `degree`, `birthdate`}},
+		},
+		{
+			name:    `no changes`,
+			e:       domain.SchemaEvolution{Version: `1.3`},
+			wantErr: true,
+		},
+		{
+			name:    `remove missing attribute`,
+			e:       domain.SchemaEvolution{RemoveAttributes: []string{`gpa`}},
+			wantErr: true,
+		},
+		{
+			name:    `add existing attribute`,
+			e:       domain.SchemaEvolution{AddAttributes: []string{`Degree`}},
+			wantErr: true,
+		},
+		{
+			name:    `lower version`,
+			e:       domain.SchemaEvolution{AddAttributes: []string{`gpa`}, Version: `1.1`},
+			wantErr: true,
+		},
+		{
+			name:    `same version`,
+			e:       domain.SchemaEvolution{AddAttributes: []string{`gpa`}, Version: `1.2.0`},
+			wantErr: true,
+		},
+		{
+			name:    `remove all attributes`,
+			e:       domain.SchemaEvolution{RemoveAttributes: []string{`name`, `degree`, `Date Of Birth`}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := schema.Evolve(tc.e)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf(`expected an error but got %+v`, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf(`evolve schema - %v`, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf(`got %+v, want %+v`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextSchemaVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{version: `1.0`, want: `1.1`},
+		{version: `1.9`, want: `1.10`},
+		{version: `1.2.5`, want: `1.3.0`},
+		{version: `1`, wantErr: true},
+		{version: `1.0-beta`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			got, err := domain.NextSchemaVersion(tc.version)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf(`got error %v, want error %t`, err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf(`got %s, want %s`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompareSchemaVersions(t *testing.T) {
+	tests := []struct {
+		v1, v2 string
+		want   int // sign of the comparison
+	}{
+		{v1: `1.0`, v2: `1.0`, want: 0},
+		{v1: `1.0`, v2: `1.0.0`, want: 0},
+		{v1: `1.10`, v2: `1.9`, want: 1},
+		{v1: `1.2`, v2: `1.2.1`, want: -1},
+		{v1: `2.0`, v2: `1.99.99`, want: 1},
+		{v1: `0.9`, v2: `1.0`, want: -1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.v1+` `+tc.v2, func(t *testing.T) {
+			got := domain.CompareSchemaVersions(tc.v1, tc.v2)
+			if sign(got) != tc.want {
+				t.Errorf(`got %d, want a value with the sign of %d`, got, tc.want)
+			}
+		})
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -4,26 +4,68 @@ import (
 	"flag"
 	"fmt"
 	"github.com/YasiruR/agent/agent"
+	"github.com/YasiruR/agent/agent/events"
+	"github.com/YasiruR/agent/agent/models"
 	agentServer "github.com/YasiruR/agent/transport/agent"
 	webhookServer "github.com/YasiruR/agent/transport/webhook"
 	"github.com/tryfix/log"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func main() {
-	name, controllerPort, webhookPort, url := parseArgs()
+	cfg, controllerPort, webhookPort, bootstrap, origins, endorser := parseArgs()
 	logger := log.Constructor.Log(log.WithColors(true), log.WithLevel("DEBUG"), log.WithFilePath(true))
 
-	a := agent.New(name, url, logger)
-	go webhookServer.New(webhookPort, a, logger).Serve()
-	agentServer.New(controllerPort, a, logger).Serve()
+	a, err := agent.New(cfg, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// webhooks should be received before bootstrapping since endorsed writes are awaited by their transaction state
+	broker := events.NewBroker()
+	go webhookServer.New(webhookPort, a, broker, logger).Serve()
+
+	if endorser.ConnectionID != `` {
+		if _, err = a.SetEndorserConnection(endorser.ConnectionID, endorser.Did, endorser.Name); err != nil {
+			logger.Fatal(fmt.Sprintf(`set endorser - %v`, err))
+		}
+	}
+
+	if bootstrap != `` {
+		if _, err = a.Bootstrap(bootstrap); err != nil {
+			logger.Fatal(fmt.Sprintf(`bootstrap - %v`, err))
+		}
+	}
+
+	go a.RunSweeper()
+	go a.RunExpiryScheduler()
+	go a.RunCallbackRetrier()
+	agentServer.New(controllerPort, a, broker, origins, logger).Serve()
 }
 
-func parseArgs() (name string, controllerPort, webhookPort int, url string) {
+func parseArgs() (cfg agent.Config, controllerPort, webhookPort int, bootstrap string, origins []string, endorser models.Endorser) {
 	l := flag.String(`label`, ``, `label of the agent`)
 	cp := flag.Int(`controller_port`, 0, `port of the controller`)
 	wp := flag.Int(`webhook_port`, 0, `port of the webhook processor`)
 	u := flag.String(`agent_url`, ``, `url of the agent`)
+	d := flag.String(`data_dir`, `data`, `directory to persist controller state`)
+	ar := flag.Bool(`auto_remove`, false, `remove credential exchange records once the protocol completes`)
+	rd := flag.Int(`record_retention_days`, 0, `days to keep completed or abandoned exchange records (0 keeps them unless overridden per offer)`)
+	si := flag.Duration(`sweep_interval`, time.Hour, `interval of the exchange record sweeper`)
+	ct := flag.Duration(`credential_ttl`, 0, `duration within which a peer should answer a credential offer (0 disables expiry)`)
+	pt := flag.Duration(`proof_ttl`, 0, `duration within which a peer should answer a proof request (0 disables expiry)`)
+	et := flag.Duration(`endorsement_timeout`, 2*time.Minute, `duration to wait for endorsed transactions to be written (0 does not wait)`)
+	ec := flag.String(`endorser_connection_id`, ``, `connection ID of the endorser to be set at startup`)
+	ed := flag.String(`endorser_did`, ``, `public DID of the endorser set at startup`)
+	en := flag.String(`endorser_name`, ``, `name of the endorser set at startup`)
+	aw := flag.Bool(`endorser_auto_write`, false, `ACA-Py writes endorsed transactions itself (--auto-write-transactions)`)
+	ak := flag.String(`audit_key_file`, ``, `file holding the hex encoded key of the audit ledger (generated in data_dir if not provided)`)
+	bf := flag.String(`bootstrap`, ``, `JSON file declaring schemas and credential definitions to be ensured at startup`)
+	ch := flag.String(`callback_hosts`, ``, `comma separated hosts to which verification records may be posted`)
+	au := flag.Bool(`allow_unrevealed`, false, `present requested attributes unrevealed even if the verifier does not mark them with allow_unrevealed`)
+	wo := flag.String(`ws_origins`, ``, `comma separated origins allowed to open event websockets besides the controller host`)
 	flag.Parse()
 
 	if *cp == 0 {
@@ -39,5 +81,36 @@ func parseArgs() (name string, controllerPort, webhookPort int, url string) {
 		log.Info(fmt.Sprintf(`agent label is set to the controller port [%d] since not provided explicitly`, *cp))
 	}
 
-	return *l, *cp, *wp, *u
+	if *ec != `` && *ed == `` {
+		log.Fatal(`DID of the endorser must be specified along with its connection`)
+	}
+
+	if *wo != `` {
+		for _, o := range strings.Split(*wo, `,`) {
+			origins = append(origins, strings.TrimSpace(o))
+		}
+	}
+
+	var callbackHosts []string
+	if *ch != `` {
+		for _, h := range strings.Split(*ch, `,`) {
+			callbackHosts = append(callbackHosts, strings.TrimSpace(h))
+		}
+	}
+
+	return agent.Config{
+		Name:               *l,
+		AdminUrl:           *u,
+		DataDir:            *d,
+		AutoRemove:         *ar,
+		RecordRetention:    time.Duration(*rd) * 24 * time.Hour,
+		SweepInterval:      *si,
+		CredentialTTL:      *ct,
+		ProofTTL:           *pt,
+		EndorsementTimeout: *et,
+		AutoWriteTxns:      *aw,
+		CallbackHosts:      callbackHosts,
+		AuditKeyFile:       *ak,
+		AllowUnrevealed:    *au,
+	}, *cp, *wp, *bf, origins, models.Endorser{ConnectionID: *ec, Did: *ed, Name: *en}
 }
@@ -0,0 +1,268 @@
+package simulator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"math/big"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const timeLayout = `2006-01-02 15:04:05.999999Z`
+
+// Agent is a simulated ACA-Py instance serving the admin API
+type Agent struct {
+	label      string
+	did        string
+	verkey     string
+	network    *Network
+	router     *mux.Router
+	client     *http.Client
+	mu         *sync.Mutex
+	conns      map[string]*connRecord   // connection ID to connection map
+	credExs    map[string]*credExRecord // credential exchange ID to record map
+	presExs    map[string]*presExRecord // presentation exchange ID to record map
+	wallet     map[string]walletCred    // referent to credential map
+	webhookURL *atomic.Value
+	hookMu     *sync.Mutex
+	hookCond   *sync.Cond
+	hooks      []webhook // queued webhooks which are not delivered yet
+	closed     bool
+	pending    *sync.WaitGroup
+}
+
+type webhook struct {
+	topic   string
+	payload []byte
+}
+
+func newAgent(n *Network, label string) *Agent {
+	a := &Agent{
+		label:      label,
+		did:        newDid(),
+		verkey:     base58(randomBytes(32)),
+		network:    n,
+		router:     mux.NewRouter(),
+		client:     &http.Client{},
+		mu:         &sync.Mutex{},
+		conns:      make(map[string]*connRecord),
+		credExs:    make(map[string]*credExRecord),
+		presExs:    make(map[string]*presExRecord),
+		wallet:     make(map[string]walletCred),
+		webhookURL: &atomic.Value{},
+		hookMu:     &sync.Mutex{},
+		pending:    &sync.WaitGroup{},
+	}
+	a.webhookURL.Store(``)
+	a.hookCond = sync.NewCond(a.hookMu)
+
+	a.initRoutes()
+	go a.deliverWebhooks()
+	return a
+}
+
+func (a *Agent) initRoutes() {
+	a.router.HandleFunc(`/connections/create-invitation`, a.handleCreateInvitation).Methods(http.MethodPost)
+	a.router.HandleFunc(`/connections/receive-invitation`, a.handleReceiveInvitation).Methods(http.MethodPost)
+	a.router.HandleFunc(`/connections/{id}/accept-invitation`, a.handleAcceptInvitation).Methods(http.MethodPost)
+	a.router.HandleFunc(`/connections/{id}/accept-request`, a.handleAcceptRequest).Methods(http.MethodPost)
+	a.router.HandleFunc(`/connections/{id}`, a.handleGetConnection).Methods(http.MethodGet)
+	a.router.HandleFunc(`/discover-features/query`, a.handleDiscoverFeatures).Methods(http.MethodGet)
+	a.router.HandleFunc(`/out-of-band/create-invitation`, a.handleCreateOOBInvitation).Methods(http.MethodPost)
+
+	a.router.HandleFunc(`/wallet/did/public`, a.handlePublicDid).Methods(http.MethodGet)
+	a.router.HandleFunc(`/schemas`, a.handleCreateSchema).Methods(http.MethodPost)
+	a.router.HandleFunc(`/schemas/created`, a.handleCreatedSchemas).Methods(http.MethodGet)
+	a.router.HandleFunc(`/schemas/{id}`, a.handleGetSchema).Methods(http.MethodGet)
+	a.router.HandleFunc(`/credential-definitions`, a.handleCreateCredDef).Methods(http.MethodPost)
+	a.router.HandleFunc(`/credential-definitions/created`, a.handleCreatedCredDefs).Methods(http.MethodGet)
+	a.router.HandleFunc(`/credential-definitions/{id}`, a.handleGetCredDef).Methods(http.MethodGet)
+
+	a.router.HandleFunc(`/issue-credential-2.0/send-offer`, a.handleSendOffer).Methods(http.MethodPost)
+	a.router.HandleFunc(`/issue-credential-2.0/send`, a.handleSendCredential).Methods(http.MethodPost)
+	a.router.HandleFunc(`/issue-credential-2.0/create-offer`, a.handleCreateOffer).Methods(http.MethodPost)
+	a.router.HandleFunc(`/issue-credential-2.0/records`, a.handleCredRecords).Methods(http.MethodGet)
+	a.router.HandleFunc(`/issue-credential-2.0/records/{id}`, a.handleCredRecord).Methods(http.MethodGet)
+	a.router.HandleFunc(`/issue-credential-2.0/records/{id}`, a.handleDeleteCredRecord).Methods(http.MethodDelete)
+	a.router.HandleFunc(`/issue-credential-2.0/records/{id}/send-request`, a.handleSendCredRequest).Methods(http.MethodPost)
+	a.router.HandleFunc(`/issue-credential-2.0/records/{id}/issue`, a.handleIssue).Methods(http.MethodPost)
+	a.router.HandleFunc(`/issue-credential-2.0/records/{id}/store`, a.handleStore).Methods(http.MethodPost)
+	a.router.HandleFunc(`/issue-credential-2.0/records/{id}/problem-report`, a.handleCredProblemReport).Methods(http.MethodPost)
+	a.router.HandleFunc(`/credentials`, a.handleCredentials).Methods(http.MethodGet)
+	a.router.HandleFunc(`/credential/{id}`, a.handleCredential).Methods(http.MethodGet)
+	a.router.HandleFunc(`/credential/{id}`, a.handleDeleteCredential).Methods(http.MethodDelete)
+	a.router.HandleFunc(`/credential/revoked/{id}`, a.handleRevokedCredential).Methods(http.MethodGet)
+
+	a.router.HandleFunc(`/present-proof-2.0/send-request`, a.handleSendProofRequest).Methods(http.MethodPost)
+	a.router.HandleFunc(`/present-proof-2.0/create-request`, a.handleCreateProofRequest).Methods(http.MethodPost)
+	a.router.HandleFunc(`/present-proof-2.0/records`, a.handlePresRecords).Methods(http.MethodGet)
+	a.router.HandleFunc(`/present-proof-2.0/records/{id}`, a.handlePresRecord).Methods(http.MethodGet)
+	a.router.HandleFunc(`/present-proof-2.0/records/{id}`, a.handleDeletePresRecord).Methods(http.MethodDelete)
+	a.router.HandleFunc(`/present-proof-2.0/records/{id}/credentials`, a.handlePresCredentials).Methods(http.MethodGet)
+	a.router.HandleFunc(`/present-proof-2.0/records/{id}/send-presentation`, a.handleSendPresentation).Methods(http.MethodPost)
+	a.router.HandleFunc(`/present-proof-2.0/records/{id}/verify-presentation`, a.handleVerifyPresentation).Methods(http.MethodPost)
+	a.router.HandleFunc(`/present-proof-2.0/records/{id}/problem-report`, a.handlePresProblemReport).Methods(http.MethodPost)
+}
+
+func (a *Agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.router.ServeHTTP(w, r)
+}
+
+func (a *Agent) Label() string {
+	return a.label
+}
+
+// PublicDid returns the DID of the agent which is used to publish schemas and credential definitions
+func (a *Agent) PublicDid() string {
+	return a.did
+}
+
+// SetWebhookURL sets the base URL to which the webhooks are posted as <url>/topic/<topic>/
+func (a *Agent) SetWebhookURL(url string) {
+	a.webhookURL.Store(url)
+}
+
+// Flush blocks until all webhooks emitted so far have been delivered
+func (a *Agent) Flush() {
+	a.pending.Wait()
+}
+
+// Close stops delivering webhooks
+func (a *Agent) Close() {
+	a.hookMu.Lock()
+	defer a.hookMu.Unlock()
+
+	a.closed = true
+	a.hookCond.Signal()
+}
+
+// emit queues the webhook of a record so that webhooks are delivered in order without blocking the admin API,
+// since the controller may call the admin API while handling a webhook
+func (a *Agent) emit(topic string, record interface{}) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	a.hookMu.Lock()
+	defer a.hookMu.Unlock()
+	if a.closed {
+		return
+	}
+
+	a.pending.Add(1)
+	a.hooks = append(a.hooks, webhook{topic: topic, payload: payload})
+	a.hookCond.Signal()
+}
+
+func (a *Agent) deliverWebhooks() {
+	for {
+		a.hookMu.Lock()
+		for len(a.hooks) == 0 && !a.closed {
+			a.hookCond.Wait()
+		}
+		if a.closed {
+			for range a.hooks {
+				a.pending.Done()
+			}
+			a.hooks = nil
+			a.hookMu.Unlock()
+			return
+		}
+		h := a.hooks[0]
+		a.hooks = a.hooks[1:]
+		a.hookMu.Unlock()
+
+		if url := a.webhookURL.Load().(string); url != `` {
+			res, err := a.client.Post(url+`/topic/`+h.topic+`/`, `application/json`, bytes.NewBuffer(h.payload))
+			if err == nil {
+				res.Body.Close()
+			}
+		}
+		a.pending.Done()
+	}
+}
+
+func readJSON(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf(`invalid request body - %v`, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// writeError responds with the plain text error similar to the admin API
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(err.Error()))
+}
+
+func now() string {
+	return time.Now().UTC().Format(timeLayout)
+}
+
+func newID() string {
+	b := randomBytes(16)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[:8] + `-` + h[8:12] + `-` + h[12:16] + `-` + h[16:20] + `-` + h[20:]
+}
+
+func newDid() string {
+	return base58(randomBytes(16))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf(`reading random bytes - %v`, err))
+	}
+
+	return b
+}
+
+const base58Alphabet = `123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz`
+
+func base58(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	base, mod := big.NewInt(58), new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return string(out)
+}
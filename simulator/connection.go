@@ -0,0 +1,308 @@
+package simulator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+const (
+	topicConnections     = `connections`
+	topicDiscoverFeature = `discover_feature`
+	connStateActive      = `active`
+)
+
+// protocols disclosed by simulated agents
+var supportedProtocols = []string{
+	`https://didcomm.org/connections/1.0`,
+	`https://didcomm.org/issue-credential/2.0`,
+	`https://didcomm.org/present-proof/2.0`,
+	`https://didcomm.org/out-of-band/1.0`,
+}
+
+type connRecord struct {
+	ConnectionID    string `json:"connection_id"`
+	Accept          string `json:"accept"`
+	Alias           string `json:"alias,omitempty"`
+	InvitationKey   string `json:"invitation_key,omitempty"`
+	InvitationMode  string `json:"invitation_mode"`
+	InvitationMsgID string `json:"invitation_msg_id,omitempty"`
+	MyDid           string `json:"my_did,omitempty"`
+	TheirDid        string `json:"their_did,omitempty"`
+	TheirLabel      string `json:"their_label,omitempty"`
+	TheirRole       string `json:"their_role"`
+	State           string `json:"state"`
+	Rfc23State      string `json:"rfc23_state"`
+	RoutingState    string `json:"routing_state"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+	peer            *Agent
+	peerConnID      string
+}
+
+func (a *Agent) handleCreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Alias   string `json:"alias"`
+		MyLabel string `json:"my_label"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	label := a.label
+	if req.MyLabel != `` {
+		label = req.MyLabel
+	}
+
+	inv := domain.Invitation{
+		ID:              newID(),
+		Type:            `https://didcomm.org/connections/1.0/invitation`,
+		Label:           label,
+		RecipientKeys:   []string{base58(randomBytes(32))},
+		ServiceEndpoint: `sim://` + a.label,
+	}
+
+	a.mu.Lock()
+	conn := &connRecord{
+		ConnectionID:    newID(),
+		Accept:          `manual`,
+		Alias:           req.Alias,
+		InvitationKey:   inv.RecipientKeys[0],
+		InvitationMode:  `once`,
+		InvitationMsgID: inv.ID,
+		TheirRole:       `invitee`,
+		State:           `invitation`,
+		Rfc23State:      `invitation-sent`,
+		RoutingState:    `none`,
+		CreatedAt:       now(),
+		UpdatedAt:       now(),
+	}
+	a.conns[conn.ConnectionID] = conn
+	a.emit(topicConnections, conn)
+	a.mu.Unlock()
+
+	a.network.addInvitation(inv.ID, a, conn.ConnectionID)
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, struct {
+		ConnectionID  string            `json:"connection_id"`
+		Invitation    domain.Invitation `json:"invitation"`
+		InvitationURL string            `json:"invitation_url"`
+	}{
+		ConnectionID:  conn.ConnectionID,
+		Invitation:    inv,
+		InvitationURL: inv.ServiceEndpoint + `?c_i=` + base64.URLEncoding.EncodeToString(data),
+	})
+}
+
+func (a *Agent) handleReceiveInvitation(w http.ResponseWriter, r *http.Request) {
+	var inv domain.Invitation
+	if err := readJSON(r, &inv); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ref, ok := a.network.takeInvitation(inv.ID)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`invitation %s is unknown or has already been used`, inv.ID))
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn := &connRecord{
+		ConnectionID:    newID(),
+		Accept:          `manual`,
+		InvitationKey:   firstOf(inv.RecipientKeys),
+		InvitationMode:  `once`,
+		InvitationMsgID: inv.ID,
+		TheirLabel:      inv.Label,
+		TheirRole:       `inviter`,
+		State:           `invitation`,
+		Rfc23State:      `invitation-received`,
+		RoutingState:    `none`,
+		CreatedAt:       now(),
+		UpdatedAt:       now(),
+		peer:            ref.agent,
+		peerConnID:      ref.connID,
+	}
+	a.conns[conn.ConnectionID] = conn
+	a.emit(topicConnections, conn)
+	writeJSON(w, conn)
+}
+
+func (a *Agent) handleAcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	label := r.URL.Query().Get(`my_label`)
+	if label == `` {
+		label = a.label
+	}
+
+	a.mu.Lock()
+	conn, ok := a.conns[mux.Vars(r)[`id`]]
+	if !ok || conn.State != `invitation` || conn.TheirRole != `inviter` {
+		a.mu.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf(`no received invitation found for connection %s`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	conn.MyDid = newDid()
+	a.setConnState(conn, `request`, `request-sent`)
+	res, peer, peerConnID := *conn, conn.peer, conn.peerConnID
+	a.mu.Unlock()
+
+	if err := peer.receiveConnRequest(peerConnID, a, res.ConnectionID, label, res.MyDid); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, res)
+}
+
+func (a *Agent) receiveConnRequest(connID string, peer *Agent, peerConnID, label, did string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn, ok := a.conns[connID]
+	if !ok || conn.State != `invitation` {
+		return fmt.Errorf(`invitation of connection %s is not pending`, connID)
+	}
+
+	conn.TheirLabel = label
+	conn.TheirDid = did
+	conn.peer = peer
+	conn.peerConnID = peerConnID
+	a.setConnState(conn, `request`, `request-received`)
+	return nil
+}
+
+func (a *Agent) handleAcceptRequest(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	conn, ok := a.conns[mux.Vars(r)[`id`]]
+	if !ok || conn.Rfc23State != `request-received` {
+		a.mu.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf(`no connection request found for connection %s`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	conn.MyDid = newDid()
+	a.setConnState(conn, `response`, `response-sent`)
+	peer, peerConnID, did := conn.peer, conn.peerConnID, conn.MyDid
+	a.mu.Unlock()
+
+	// the invitee completes the connection with a trust ping which activates the connection of the inviter
+	peer.receiveConnResponse(peerConnID, did)
+
+	a.mu.Lock()
+	a.setConnState(conn, connStateActive, `completed`)
+	res := *conn
+	a.mu.Unlock()
+
+	writeJSON(w, res)
+}
+
+func (a *Agent) receiveConnResponse(connID, did string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn, ok := a.conns[connID]
+	if !ok {
+		return
+	}
+
+	conn.TheirDid = did
+	a.setConnState(conn, `response`, `response-received`)
+	a.setConnState(conn, connStateActive, `completed`)
+}
+
+func (a *Agent) handleGetConnection(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn, ok := a.conns[mux.Vars(r)[`id`]]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`connection %s not found`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	writeJSON(w, conn)
+}
+
+// handleDiscoverFeatures discloses the protocols of the peer via the discover_feature webhook
+func (a *Agent) handleDiscoverFeatures(w http.ResponseWriter, r *http.Request) {
+	connID := r.URL.Query().Get(`connection_id`)
+	if _, _, err := a.activeConn(connID); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	type protocol struct {
+		Pid   string   `json:"pid"`
+		Roles []string `json:"roles"`
+	}
+
+	var rec struct {
+		ConnectionID        string `json:"connection_id"`
+		DiscoveryExchangeID string `json:"discovery_exchange_id"`
+		Disclose            struct {
+			ID        string     `json:"@id"`
+			Type      string     `json:"@type"`
+			Protocols []protocol `json:"protocols"`
+		} `json:"disclose"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}
+	rec.ConnectionID = connID
+	rec.DiscoveryExchangeID = newID()
+	rec.Disclose.ID = newID()
+	rec.Disclose.Type = `https://didcomm.org/discover-features/1.0/disclose`
+	for _, pid := range supportedProtocols {
+		rec.Disclose.Protocols = append(rec.Disclose.Protocols, protocol{Pid: pid})
+	}
+	rec.CreatedAt, rec.UpdatedAt = now(), now()
+
+	a.emit(topicDiscoverFeature, rec)
+	writeJSON(w, rec)
+}
+
+// activeConn returns the peer agent and its connection ID of an active connection
+func (a *Agent) activeConn(connID string) (*Agent, string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn, ok := a.conns[connID]
+	if !ok {
+		return nil, ``, fmt.Errorf(`connection %s not found`, connID)
+	}
+
+	if conn.State != connStateActive {
+		return nil, ``, fmt.Errorf(`connection %s is not ready [%s]`, connID, conn.State)
+	}
+
+	return conn.peer, conn.peerConnID, nil
+}
+
+// setConnState updates the state of the connection and emits the webhook, where the lock should be held by the caller
+func (a *Agent) setConnState(conn *connRecord, state, rfc23State string) {
+	conn.State = state
+	conn.Rfc23State = rfc23State
+	conn.UpdatedAt = now()
+	a.emit(topicConnections, conn)
+}
+
+func firstOf(list []string) string {
+	if len(list) == 0 {
+		return ``
+	}
+
+	return list[0]
+}
@@ -0,0 +1,620 @@
+package simulator
+
+import (
+	"fmt"
+	"github.com/YasiruR/agent/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const (
+	topicIssueCredential     = `issue_credential_v2_0`
+	topicIssueCredentialIndy = `issue_credential_v2_0_indy`
+	roleIssuer               = `issuer`
+	roleHolder               = `holder`
+	stateDeleted             = `deleted`
+	stateAbandoned           = `abandoned`
+	stateDone                = `done`
+)
+
+type credExRecord struct {
+	CredExID     string                    `json:"cred_ex_id"`
+	ConnID       string                    `json:"conn_id,omitempty"`
+	ThreadID     string                    `json:"thread_id"`
+	Role         string                    `json:"role"`
+	Initiator    string                    `json:"initiator"`
+	State        string                    `json:"state"`
+	AutoOffer    bool                      `json:"auto_offer"`
+	AutoIssue    bool                      `json:"auto_issue"`
+	AutoRemove   bool                      `json:"auto_remove"`
+	CredPreview  *domain.CredentialPreview `json:"cred_preview,omitempty"`
+	CredOffer    *credMessage              `json:"cred_offer,omitempty"`
+	CredIssue    *credMessage              `json:"cred_issue,omitempty"`
+	ByFormat     credByFormat              `json:"by_format"`
+	CredIDStored string                    `json:"cred_id_stored,omitempty"`
+	ErrorMsg     string                    `json:"error_msg,omitempty"`
+	CreatedAt    string                    `json:"created_at"`
+	UpdatedAt    string                    `json:"updated_at"`
+	peer         *Agent
+	autoStore    bool
+	issued       *walletCred // credential received by the holder which is not stored yet
+}
+
+type credMessage struct {
+	ID                string                    `json:"@id"`
+	Type              string                    `json:"@type"`
+	Comment           string                    `json:"comment"`
+	CredentialPreview *domain.CredentialPreview `json:"credential_preview,omitempty"`
+	Thread            struct {
+		Thid string `json:"thid,omitempty"`
+	} `json:"~thread"`
+}
+
+type credByFormat struct {
+	CredOffer struct {
+		Indy *indyCredOffer `json:"indy,omitempty"`
+	} `json:"cred_offer"`
+}
+
+type indyCredOffer struct {
+	CredDefID string `json:"cred_def_id"`
+	SchemaID  string `json:"schema_id"`
+}
+
+// walletCred is a credential stored in the wallet of a holder
+type walletCred struct {
+	Referent  string            `json:"referent"`
+	Attrs     map[string]string `json:"attrs"`
+	SchemaID  string            `json:"schema_id"`
+	CredDefID string            `json:"cred_def_id"`
+	RevRegID  *string           `json:"rev_reg_id"`
+	CredRevID *string           `json:"cred_rev_id"`
+}
+
+type offerRequest struct {
+	AutoIssue         bool                     `json:"auto_issue"`
+	AutoRemove        bool                     `json:"auto_remove"`
+	Comment           string                   `json:"comment"`
+	ConnectionID      string                   `json:"connection_id"`
+	CredentialPreview domain.CredentialPreview `json:"credential_preview"`
+	Filter            struct {
+		Indy domain.IndySchemaMeta `json:"indy"`
+	} `json:"filter"`
+}
+
+func (a *Agent) handleSendOffer(w http.ResponseWriter, r *http.Request) {
+	a.sendOffer(w, r, false)
+}
+
+// handleSendCredential runs the whole exchange automatically, where the holder requests and stores the credential
+// without any intervention of its controller
+func (a *Agent) handleSendCredential(w http.ResponseWriter, r *http.Request) {
+	a.sendOffer(w, r, true)
+}
+
+func (a *Agent) sendOffer(w http.ResponseWriter, r *http.Request, automated bool) {
+	var req offerRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	peer, peerConnID, err := a.activeConn(req.ConnectionID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rec, err := a.newOffer(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rec.ConnID = req.ConnectionID
+	rec.AutoIssue = rec.AutoIssue || automated
+	rec.peer = peer
+
+	a.mu.Lock()
+	a.credExs[rec.CredExID] = rec
+	a.emit(topicIssueCredential, rec)
+	res := *rec
+	a.mu.Unlock()
+
+	peer.receiveOffer(peerConnID, a, res, automated)
+	writeJSON(w, res)
+}
+
+// handleCreateOffer creates an offer which is not bound to a connection to be sent as an out-of-band attachment
+func (a *Agent) handleCreateOffer(w http.ResponseWriter, r *http.Request) {
+	var req offerRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rec, err := a.newOffer(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	a.mu.Lock()
+	a.credExs[rec.CredExID] = rec
+	a.emit(topicIssueCredential, rec)
+	res := *rec
+	a.mu.Unlock()
+
+	writeJSON(w, res)
+}
+
+// newOffer validates the offer against the credential definition of this agent and the attributes of its schema
+func (a *Agent) newOffer(req offerRequest) (*credExRecord, error) {
+	def, ok := a.network.credDef(req.Filter.Indy.CredDefID)
+	if !ok {
+		return nil, fmt.Errorf(`credential definition %s not found on the ledger`, req.Filter.Indy.CredDefID)
+	}
+
+	if def.IssuerDid != a.did {
+		return nil, fmt.Errorf(`credential definition %s is not owned by this agent`, def.ID)
+	}
+
+	schema, _ := a.network.schema(def.SchemaID)
+	if err := matchSchema(schema, req.CredentialPreview); err != nil {
+		return nil, err
+	}
+
+	preview := req.CredentialPreview
+	offer := &credMessage{
+		ID:                newID(),
+		Type:              `https://didcomm.org/issue-credential/2.0/offer-credential`,
+		Comment:           req.Comment,
+		CredentialPreview: &preview,
+	}
+
+	rec := &credExRecord{
+		CredExID:    newID(),
+		ThreadID:    offer.ID,
+		Role:        roleIssuer,
+		Initiator:   `self`,
+		State:       `offer-sent`,
+		AutoIssue:   req.AutoIssue,
+		AutoRemove:  req.AutoRemove,
+		CredPreview: &preview,
+		CredOffer:   offer,
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+	}
+	rec.ByFormat.CredOffer.Indy = &indyCredOffer{CredDefID: def.ID, SchemaID: def.SchemaID}
+	return rec, nil
+}
+
+func (a *Agent) receiveOffer(connID string, issuer *Agent, offer credExRecord, automated bool) {
+	a.mu.Lock()
+	rec := &credExRecord{
+		CredExID:    newID(),
+		ConnID:      connID,
+		ThreadID:    offer.ThreadID,
+		Role:        roleHolder,
+		Initiator:   `external`,
+		State:       `offer-received`,
+		CredPreview: offer.CredPreview,
+		CredOffer:   offer.CredOffer,
+		ByFormat:    offer.ByFormat,
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+		peer:        issuer,
+		autoStore:   automated,
+	}
+	a.credExs[rec.CredExID] = rec
+	a.emit(topicIssueCredential, rec)
+	a.mu.Unlock()
+
+	if automated {
+		if _, err := a.requestCredential(rec.CredExID); err != nil {
+			a.abandonCredEx(rec.CredExID, err)
+		}
+	}
+}
+
+func (a *Agent) handleSendCredRequest(w http.ResponseWriter, r *http.Request) {
+	res, err := a.requestCredential(mux.Vars(r)[`id`])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, res)
+}
+
+func (a *Agent) requestCredential(credExID string) (credExRecord, error) {
+	a.mu.Lock()
+	rec, err := a.credEx(credExID, roleHolder, `offer-received`)
+	if err != nil {
+		a.mu.Unlock()
+		return credExRecord{}, err
+	}
+
+	a.setCredState(rec, `request-sent`)
+	res := *rec
+	a.mu.Unlock()
+
+	res.peer.receiveCredRequest(res.ThreadID)
+	return res, nil
+}
+
+func (a *Agent) receiveCredRequest(threadID string) {
+	a.mu.Lock()
+	rec := a.credExByThread(threadID, roleIssuer)
+	if rec == nil {
+		a.mu.Unlock()
+		return
+	}
+
+	a.setCredState(rec, `request-received`)
+	id, auto := rec.CredExID, rec.AutoIssue
+	a.mu.Unlock()
+
+	if auto {
+		if _, err := a.issue(id); err != nil {
+			a.abandonCredEx(id, err)
+		}
+	}
+}
+
+func (a *Agent) handleIssue(w http.ResponseWriter, r *http.Request) {
+	res, err := a.issue(mux.Vars(r)[`id`])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, res)
+}
+
+func (a *Agent) issue(credExID string) (credExRecord, error) {
+	a.mu.Lock()
+	rec, err := a.credEx(credExID, roleIssuer, `request-received`)
+	if err != nil {
+		a.mu.Unlock()
+		return credExRecord{}, err
+	}
+
+	rec.CredIssue = &credMessage{ID: newID(), Type: `https://didcomm.org/issue-credential/2.0/issue-credential`}
+	rec.CredIssue.Thread.Thid = rec.ThreadID
+	a.setCredState(rec, `credential-issued`)
+	res := *rec
+	a.mu.Unlock()
+
+	cred := walletCred{Attrs: make(map[string]string), SchemaID: res.ByFormat.CredOffer.Indy.SchemaID, CredDefID: res.ByFormat.CredOffer.Indy.CredDefID}
+	for _, attr := range res.CredPreview.Attributes {
+		cred.Attrs[attr.Name] = attr.Value
+	}
+
+	res.peer.receiveCredential(res.ThreadID, *res.CredIssue, cred)
+	return res, nil
+}
+
+func (a *Agent) receiveCredential(threadID string, msg credMessage, cred walletCred) {
+	a.mu.Lock()
+	rec := a.credExByThread(threadID, roleHolder)
+	if rec == nil {
+		a.mu.Unlock()
+		return
+	}
+
+	rec.CredIssue = &msg
+	rec.issued = &cred
+	a.setCredState(rec, `credential-received`)
+	id, auto := rec.CredExID, rec.autoStore
+	a.mu.Unlock()
+
+	if auto {
+		if _, err := a.storeCredential(id); err != nil {
+			a.abandonCredEx(id, err)
+		}
+	}
+}
+
+func (a *Agent) handleStore(w http.ResponseWriter, r *http.Request) {
+	res, err := a.storeCredential(mux.Vars(r)[`id`])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, res)
+}
+
+func (a *Agent) storeCredential(credExID string) (credExRecord, error) {
+	a.mu.Lock()
+	rec, err := a.credEx(credExID, roleHolder, `credential-received`)
+	if err != nil {
+		a.mu.Unlock()
+		return credExRecord{}, err
+	}
+
+	cred := *rec.issued
+	cred.Referent = newID()
+	a.wallet[cred.Referent] = cred
+	rec.CredIDStored = cred.Referent
+	rec.issued = nil
+	a.emit(topicIssueCredentialIndy, struct {
+		CredExIndyID string  `json:"cred_ex_indy_id"`
+		CredExID     string  `json:"cred_ex_id"`
+		CredIDStored string  `json:"cred_id_stored"`
+		RevRegID     *string `json:"rev_reg_id"`
+		CreatedAt    string  `json:"created_at"`
+		UpdatedAt    string  `json:"updated_at"`
+	}{CredExIndyID: newID(), CredExID: rec.CredExID, CredIDStored: cred.Referent, RevRegID: cred.RevRegID, CreatedAt: now(), UpdatedAt: now()})
+	a.setCredState(rec, stateDone)
+	res := *rec
+	a.mu.Unlock()
+
+	res.peer.receiveCredAck(res.ThreadID)
+	return res, nil
+}
+
+func (a *Agent) receiveCredAck(threadID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec := a.credExByThread(threadID, roleIssuer)
+	if rec == nil {
+		return
+	}
+
+	a.setCredState(rec, stateDone)
+	if rec.AutoRemove {
+		a.deleteCredEx(rec)
+	}
+}
+
+func (a *Agent) handleCredProblemReport(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Description string `json:"description"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	a.mu.Lock()
+	rec, err := a.credEx(mux.Vars(r)[`id`], ``, ``)
+	if err != nil {
+		a.mu.Unlock()
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	rec.ErrorMsg = req.Description
+	a.setCredState(rec, stateAbandoned)
+	peer, threadID, role := rec.peer, rec.ThreadID, rec.Role
+	a.mu.Unlock()
+
+	if peer != nil {
+		peer.receiveCredProblemReport(threadID, oppositeRole(role), req.Description)
+	}
+	writeJSON(w, struct{}{})
+}
+
+func (a *Agent) receiveCredProblemReport(threadID, role, description string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if rec := a.credExByThread(threadID, role); rec != nil {
+		rec.ErrorMsg = description
+		a.setCredState(rec, stateAbandoned)
+	}
+}
+
+// abandonCredEx marks the exchange as abandoned when an automated step fails
+func (a *Agent) abandonCredEx(credExID string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if rec, ok := a.credExs[credExID]; ok {
+		rec.ErrorMsg = err.Error()
+		a.setCredState(rec, stateAbandoned)
+	}
+}
+
+// handleCredRecords lists the records wrapped along with their format specific records as done by the admin API
+func (a *Agent) handleCredRecords(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	results := []interface{}{}
+	for _, rec := range a.sortedCredExs() {
+		if (params.Get(`connection_id`) != `` && rec.ConnID != params.Get(`connection_id`)) ||
+			(params.Get(`role`) != `` && rec.Role != params.Get(`role`)) ||
+			(params.Get(`state`) != `` && rec.State != params.Get(`state`)) ||
+			(params.Get(`thread_id`) != `` && rec.ThreadID != params.Get(`thread_id`)) {
+			continue
+		}
+		results = append(results, wrapCredEx(rec))
+	}
+
+	writeJSON(w, map[string]interface{}{`results`: results})
+}
+
+func (a *Agent) handleCredRecord(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec, err := a.credEx(mux.Vars(r)[`id`], ``, ``)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeJSON(w, wrapCredEx(rec))
+}
+
+func (a *Agent) handleDeleteCredRecord(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec, err := a.credEx(mux.Vars(r)[`id`], ``, ``)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	a.deleteCredEx(rec)
+	writeJSON(w, struct{}{})
+}
+
+func (a *Agent) handleCredentials(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	var creds []walletCred
+	for _, c := range a.wallet {
+		creds = append(creds, c)
+	}
+	a.mu.Unlock()
+
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Referent < creds[j].Referent })
+	start, _ := strconv.Atoi(r.URL.Query().Get(`start`))
+	count, err := strconv.Atoi(r.URL.Query().Get(`count`))
+	if err != nil {
+		count = 10
+	}
+
+	results := []walletCred{}
+	for i := start; i < len(creds) && i < start+count; i++ {
+		results = append(results, creds[i])
+	}
+
+	writeJSON(w, map[string][]walletCred{`results`: results})
+}
+
+func (a *Agent) handleCredential(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cred, ok := a.wallet[mux.Vars(r)[`id`]]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`credential %s not found`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	writeJSON(w, cred)
+}
+
+// handleRevokedCredential reports the revocation status of a credential in the wallet, where credentials are never
+// revoked as revocation registries are not simulated
+func (a *Agent) handleRevokedCredential(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	_, ok := a.wallet[mux.Vars(r)[`id`]]
+	a.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`credential %s not found in the wallet`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	writeJSON(w, map[string]bool{`revoked`: false})
+}
+
+func (a *Agent) handleDeleteCredential(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.wallet[mux.Vars(r)[`id`]]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`credential %s not found`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	delete(a.wallet, mux.Vars(r)[`id`])
+	writeJSON(w, struct{}{})
+}
+
+// credEx returns the record of the exchange, which should be of the given role and state unless they are empty.
+// The lock should be held by the caller.
+func (a *Agent) credEx(credExID, role, state string) (*credExRecord, error) {
+	rec, ok := a.credExs[credExID]
+	if !ok {
+		return nil, fmt.Errorf(`credential exchange record %s not found`, credExID)
+	}
+
+	if (role != `` && rec.Role != role) || (state != `` && rec.State != state) {
+		return nil, fmt.Errorf(`credential exchange %s is in state %s as %s while %s is expected as %s`, credExID, rec.State, rec.Role, state, role)
+	}
+
+	return rec, nil
+}
+
+// credExByThread returns the record of the given role in the thread, where the lock should be held by the caller
+func (a *Agent) credExByThread(threadID, role string) *credExRecord {
+	for _, rec := range a.credExs {
+		if rec.ThreadID == threadID && rec.Role == role {
+			return rec
+		}
+	}
+
+	return nil
+}
+
+func (a *Agent) sortedCredExs() []*credExRecord {
+	var list []*credExRecord
+	for _, rec := range a.credExs {
+		list = append(list, rec)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt < list[j].CreatedAt })
+	return list
+}
+
+func (a *Agent) setCredState(rec *credExRecord, state string) {
+	rec.State = state
+	rec.UpdatedAt = now()
+	a.emit(topicIssueCredential, rec)
+}
+
+func (a *Agent) deleteCredEx(rec *credExRecord) {
+	delete(a.credExs, rec.CredExID)
+	deleted := *rec
+	deleted.State = stateDeleted
+	a.emit(topicIssueCredential, deleted)
+}
+
+func wrapCredEx(rec *credExRecord) interface{} {
+	return struct {
+		CredExRecord *credExRecord `json:"cred_ex_record"`
+	}{CredExRecord: rec}
+}
+
+func oppositeRole(role string) string {
+	switch role {
+	case roleIssuer:
+		return roleHolder
+	case roleHolder:
+		return roleIssuer
+	case roleVerifier:
+		return roleProver
+	default:
+		return roleVerifier
+	}
+}
+
+// matchSchema checks if the attributes of the preview are the same as the ones of the schema
+func matchSchema(schema domain.LedgerSchema, preview domain.CredentialPreview) error {
+	names := make(map[string]bool)
+	for _, attr := range preview.Attributes {
+		names[attr.Name] = true
+	}
+
+	if len(names) != len(schema.AttrNames) {
+		return fmt.Errorf(`preview attributes do not match the attributes of schema %s`, schema.ID)
+	}
+
+	for _, name := range schema.AttrNames {
+		if !names[name] {
+			return fmt.Errorf(`attribute %s of schema %s is missing in the preview`, name, schema.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,378 @@
+package simulator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YasiruR/agent/agent"
+	"github.com/YasiruR/agent/agent/events"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/domain"
+	"github.com/YasiruR/agent/simulator"
+	agentServer "github.com/YasiruR/agent/transport/agent"
+	"github.com/YasiruR/agent/transport/webhook"
+	"github.com/tryfix/log"
+)
+
+// node is a controller served over httptest whose admin API is a simulated agent posting its webhooks to the
+// webhook server of the controller
+type node struct {
+	t     *testing.T
+	label string
+	url   string
+	sim   *simulator.Agent
+}
+
+type network struct {
+	t     *testing.T
+	nodes []*node
+}
+
+func newNetwork(t *testing.T, labels ...string) *network {
+	sims := simulator.NewNetwork()
+	logger := log.Constructor.Log(log.WithLevel(`ERROR`))
+	n := &network{t: t}
+
+	for _, label := range labels {
+		sim := sims.NewAgent(label)
+		admin := httptest.NewServer(sim)
+		t.Cleanup(admin.Close)
+		t.Cleanup(sim.Close)
+
+		cfg := agent.Config{
+			Name:          label,
+			AdminUrl:      admin.URL,
+			DataDir:       t.TempDir(),
+			CredentialTTL: time.Hour,
+			ProofTTL:      time.Hour,
+			CallbackHosts: []string{`127.0.0.1`},
+		}
+		a, err := agent.New(cfg, logger)
+		if err != nil {
+			t.Fatalf(`create agent %s - %v`, label, err)
+		}
+
+		broker := events.NewBroker()
+		hooks := httptest.NewServer(webhook.New(0, a, broker, logger).Handler())
+		t.Cleanup(hooks.Close)
+		sim.SetWebhookURL(hooks.URL)
+
+		api := httptest.NewServer(agentServer.New(0, a, broker, nil, logger).Handler())
+		t.Cleanup(api.Close)
+		n.nodes = append(n.nodes, &node{t: t, label: label, url: api.URL, sim: sim})
+	}
+
+	return n
+}
+
+func (n *network) node(label string) *node {
+	for _, nd := range n.nodes {
+		if nd.label == label {
+			return nd
+		}
+	}
+
+	n.t.Fatalf(`unknown node %s`, label)
+	return nil
+}
+
+// flush waits until the webhooks emitted by all agents have been processed, including those emitted while
+// processing others
+func (n *network) flush() {
+	for i := 0; i < 3; i++ {
+		for _, nd := range n.nodes {
+			nd.sim.Flush()
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// connect establishes a connection from the invitee to the inviter through their controllers
+func (n *network) connect(inviter, invitee string) {
+	var inv struct {
+		Invitation domain.Invitation `json:"invitation"`
+	}
+	n.node(inviter).post(`/invitation/create`, nil, &inv)
+	n.node(invitee).post(`/invitation/accept`, map[string]interface{}{`invitation`: inv.Invitation}, nil)
+	n.flush()
+	n.node(inviter).post(`/connection/accept-request/`+invitee, nil, nil)
+	n.flush()
+}
+
+func (nd *node) post(path string, body, out interface{}) {
+	nd.t.Helper()
+	if status := nd.do(http.MethodPost, path, body, out); status != http.StatusOK {
+		nd.t.Fatalf(`POST %s of %s responded with %d`, path, nd.label, status)
+	}
+}
+
+func (nd *node) get(path string, out interface{}) {
+	nd.t.Helper()
+	if status := nd.do(http.MethodGet, path, nil, out); status != http.StatusOK {
+		nd.t.Fatalf(`GET %s of %s responded with %d`, path, nd.label, status)
+	}
+}
+
+func (nd *node) do(method, path string, body, out interface{}) int {
+	nd.t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			nd.t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, nd.url+path, bytes.NewBuffer(data))
+	if err != nil {
+		nd.t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		nd.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	data, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		nd.t.Fatal(err)
+	}
+
+	if res.StatusCode == http.StatusOK && out != nil {
+		if err = json.Unmarshal(data, out); err != nil {
+			nd.t.Fatalf(`unmarshal response of %s %s - %v [%s]`, method, path, err, data)
+		}
+	}
+
+	return res.StatusCode
+}
+
+// issue runs the issue-credential flow step by step through the controllers of the issuer and the holder
+func (n *network) issue(issuer, holder string, meta domain.IndySchemaMeta, values map[string]string) {
+	iss, hol := n.node(issuer), n.node(holder)
+
+	offer := map[string]interface{}{
+		`credential_preview`: preview(values),
+		`filter`:             map[string]interface{}{`indy`: meta},
+	}
+	var issRec struct {
+		CredExID string `json:"cred_ex_id"`
+	}
+	iss.post(`/credential/offer/`+holder, offer, &issRec)
+	n.flush()
+
+	var holRec struct {
+		Record struct {
+			CredExID string `json:"cred_ex_id"`
+			State    string `json:"state"`
+		} `json:"cred_ex_record"`
+	}
+	hol.get(`/credential/record/`+issuer, &holRec)
+	if holRec.Record.State != `offer-received` {
+		n.t.Fatalf(`holder should have received the offer but the state is %s`, holRec.Record.State)
+	}
+
+	hol.post(`/credential/request/`+holRec.Record.CredExID, nil, nil)
+	n.flush()
+	iss.post(`/credential/issue/`+issRec.CredExID, nil, nil)
+	n.flush()
+	hol.post(`/credential/store/`+holRec.Record.CredExID, nil, nil)
+	n.flush()
+}
+
+func preview(values map[string]string) domain.CredentialPreview {
+	cp := domain.CredentialPreview{Type: `issue-credential/2.0/credential-preview`}
+	for name, val := range values {
+		cp.Attributes = append(cp.Attributes, domain.CredentialAttribute{Name: name, Value: val})
+	}
+
+	return cp
+}
+
+func ensureEmployeeCredDef(iss *node) domain.IndySchemaMeta {
+	setup := domain.CredentialDefinitionSetup{Schema: &domain.Schema{SchemaName: `employee`, SchemaVersion: `1.0`, Attributes: []string{`name`, `age`}}}
+	var res models.EnsureResult
+	iss.post(`/credential-definition/ensure`, setup, &res)
+	if !res.SchemaCreated || !res.CredDefCreated || res.Meta.CredDefID == `` {
+		iss.t.Fatalf(`schema and credential definition should have been created [%+v]`, res)
+	}
+
+	return res.Meta
+}
+
+func employeeProofRequest(meta domain.IndySchemaMeta, minAge int64) domain.PresentationRequest {
+	rs := []domain.Restriction{{CredDefID: meta.CredDefID}}
+	return domain.PresentationRequest{Indy: &domain.IndyProofRequest{
+		Name:                `employment`,
+		Version:             `1.0`,
+		RequestedAttributes: map[string]domain.Attribute{`name`: {Name: `name`, Restrictions: rs}},
+		RequestedPredicates: map[string]domain.Predicate{`adult`: {Name: `age`, PType: `>=`, PValue: minAge, Restrictions: rs}},
+	}}
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	n := newNetwork(t, `issuer`, `holder`, `verifier`)
+	n.connect(`issuer`, `holder`)
+	n.connect(`verifier`, `holder`)
+
+	meta := ensureEmployeeCredDef(n.node(`issuer`))
+	n.issue(`issuer`, `holder`, meta, map[string]string{`name`: `alice`, `age`: `30`})
+
+	var entries []models.AuditEntry
+	n.node(`issuer`).get(`/audit`, &entries)
+	if len(entries) == 0 {
+		t.Fatal(`issuance should have been recorded in the audit ledger`)
+	}
+
+	var req struct {
+		PresExID string `json:"pres_ex_id"`
+	}
+	n.node(`verifier`).post(`/proof/request/holder`, map[string]interface{}{`presentation_request`: employeeProofRequest(meta, 18)}, &req)
+	n.flush()
+
+	n.node(`holder`).post(`/proof/present/verifier`, nil, nil)
+	n.flush()
+
+	var res models.VerificationResult
+	n.node(`verifier`).post(`/proof/verify/`+req.PresExID, nil, &res)
+	if !res.Verified {
+		t.Fatalf(`presentation should have been verified [%+v]`, res)
+	}
+
+	if len(res.Attributes) != 1 || res.Attributes[0].Values[`name`] != `alice` || res.Attributes[0].Source.CredDefID != meta.CredDefID {
+		t.Fatalf(`revealed attribute should be proved by the issued credential [%+v]`, res.Attributes)
+	}
+
+	if len(res.Predicates) != 1 || !res.Predicates[0].Satisfied {
+		t.Fatalf(`predicate should be satisfied [%+v]`, res.Predicates)
+	}
+}
+
+func TestUnsatisfiedPredicate(t *testing.T) {
+	n := newNetwork(t, `issuer`, `holder`)
+	n.connect(`issuer`, `holder`)
+
+	meta := ensureEmployeeCredDef(n.node(`issuer`))
+	n.issue(`issuer`, `holder`, meta, map[string]string{`name`: `bob`, `age`: `16`})
+
+	n.node(`issuer`).post(`/proof/request/holder`, map[string]interface{}{`presentation_request`: employeeProofRequest(meta, 18)}, nil)
+	n.flush()
+
+	if status := n.node(`holder`).do(http.MethodPost, `/proof/present/issuer`, nil, nil); status == http.StatusOK {
+		t.Fatal(`presentation should fail since no credential satisfies the predicate`)
+	}
+}
+
+func TestAutoVerify(t *testing.T) {
+	n := newNetwork(t, `issuer`, `holder`)
+	n.connect(`issuer`, `holder`)
+
+	meta := ensureEmployeeCredDef(n.node(`issuer`))
+	n.issue(`issuer`, `holder`, meta, map[string]string{`name`: `carol`, `age`: `40`})
+
+	callbacks := make(chan models.VerificationRecord, 1)
+	cb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rec models.VerificationRecord
+		if err := json.NewDecoder(r.Body).Decode(&rec); err == nil {
+			callbacks <- rec
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer cb.Close()
+
+	body := map[string]interface{}{
+		`presentation_request`: employeeProofRequest(meta, 18),
+		`auto_verify`:          true,
+		`callback_url`:         `http://example.com/verifications`,
+	}
+	if code := n.node(`issuer`).do(http.MethodPost, `/proof/request/holder`, body, nil); code == http.StatusOK {
+		t.Fatalf(`callback to a host which is not allowed should be rejected [%d]`, code)
+	}
+
+	body[`callback_url`] = cb.URL
+	var req struct {
+		PresExID string `json:"pres_ex_id"`
+	}
+	n.node(`issuer`).post(`/proof/request/holder`, body, &req)
+	n.flush()
+
+	n.node(`holder`).post(`/proof/present/issuer`, nil, nil)
+	n.flush()
+
+	select {
+	case rec := <-callbacks:
+		if rec.PresExID != req.PresExID || rec.Result == nil || !rec.Result.Verified {
+			t.Fatalf(`callback should carry the verified result of the exchange [%+v]`, rec)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal(`verification was not posted to the callback`)
+	}
+
+	var rec models.VerificationRecord
+	n.node(`issuer`).get(`/proof/verification/`+req.PresExID, &rec)
+	if rec.Result == nil || !rec.Result.Verified {
+		t.Fatalf(`verification record should hold the verified result [%+v]`, rec)
+	}
+}
+
+func TestSchemaFamilyProof(t *testing.T) {
+	n := newNetwork(t, `issuer`, `holder`, `verifier`)
+	n.connect(`issuer`, `holder`)
+	n.connect(`verifier`, `holder`)
+
+	root := ensureEmployeeCredDef(n.node(`issuer`))
+	var next models.SchemaVersion
+	n.node(`issuer`).post(`/schema/evolve`, map[string]interface{}{`schema_id`: root.SchemaID, `add_attributes`: []string{`role`}}, &next)
+	if next.PreviousSchemaID != root.SchemaID {
+		t.Fatalf(`evolved version should refer to its predecessor [%+v]`, next)
+	}
+
+	n.issue(`issuer`, `holder`, next.Meta, map[string]string{`name`: `dave`, `age`: `35`, `role`: `engineer`})
+
+	// the verifier has no lineage of the family and relies on the issuer of the schema instead
+	pr := domain.PresentationRequest{Indy: &domain.IndyProofRequest{
+		Name:                `employment`,
+		Version:             `1.0`,
+		RequestedAttributes: map[string]domain.Attribute{`name`: {Name: `name`, SchemaFamily: `employee`, SchemaFamilyIssuer: root.SchemaIssuerDid}},
+	}}
+	var req struct {
+		PresExID string `json:"pres_ex_id"`
+	}
+	n.node(`verifier`).post(`/proof/request/holder`, map[string]interface{}{`presentation_request`: pr}, &req)
+	n.flush()
+
+	n.node(`holder`).post(`/proof/present/verifier`, nil, nil)
+	n.flush()
+
+	var res models.VerificationResult
+	n.node(`verifier`).post(`/proof/verify/`+req.PresExID, nil, &res)
+	if !res.Verified || len(res.Attributes) != 1 || res.Attributes[0].Source.SchemaID != next.Meta.SchemaID {
+		t.Fatalf(`attribute should be proved by the credential of the evolved schema [%+v]`, res)
+	}
+}
+
+func TestSelectionInputs(t *testing.T) {
+	n := newNetwork(t, `issuer`, `holder`)
+	n.connect(`issuer`, `holder`)
+
+	meta := ensureEmployeeCredDef(n.node(`issuer`))
+	n.issue(`issuer`, `holder`, meta, map[string]string{`name`: `erin`, `age`: `25`})
+
+	if status := n.node(`holder`).do(http.MethodGet, `/proof/unknown/candidates`, nil, nil); status != http.StatusNotFound {
+		t.Fatalf(`candidates of an unknown exchange should not be found but responded with %d`, status)
+	}
+
+	n.node(`issuer`).post(`/proof/request/holder`, map[string]interface{}{`presentation_request`: employeeProofRequest(meta, 18)}, nil)
+	n.flush()
+
+	choices := map[string]interface{}{`choices`: map[string]string{`department`: `any`}}
+	if status := n.node(`holder`).do(http.MethodPost, `/proof/present/issuer`, choices, nil); status == http.StatusOK {
+		t.Fatal(`choices for referents which are not requested should be rejected`)
+	}
+}
@@ -0,0 +1,140 @@
+package simulator
+
+import (
+	"fmt"
+	"github.com/YasiruR/agent/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func (a *Agent) handlePublicDid(w http.ResponseWriter, _ *http.Request) {
+	var res struct {
+		Result struct {
+			Did     string `json:"did"`
+			Verkey  string `json:"verkey"`
+			Posture string `json:"posture"`
+		} `json:"result"`
+	}
+	res.Result.Did, res.Result.Verkey, res.Result.Posture = a.did, a.verkey, `posted`
+	writeJSON(w, res)
+}
+
+// handleCreateSchema responds with the schema both at the top level and within `sent` as done by different
+// versions of the admin API
+func (a *Agent) handleCreateSchema(w http.ResponseWriter, r *http.Request) {
+	var req domain.Schema
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.SchemaName == `` || req.SchemaVersion == `` || len(req.Attributes) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`schema name, version and attributes are required`))
+		return
+	}
+
+	schema, err := a.network.publishSchema(a.did, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	type sent struct {
+		SchemaID string              `json:"schema_id"`
+		Schema   domain.LedgerSchema `json:"schema"`
+	}
+	writeJSON(w, struct {
+		sent
+		Sent sent `json:"sent"`
+	}{sent: sent{SchemaID: schema.ID, Schema: schema}, Sent: sent{SchemaID: schema.ID, Schema: schema}})
+}
+
+func (a *Agent) handleCreatedSchemas(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if did := params.Get(`schema_issuer_did`); did != `` && did != a.did {
+		writeJSON(w, map[string][]string{`schema_ids`: {}})
+		return
+	}
+
+	ids := a.network.schemasOf(a.did, params.Get(`schema_name`), params.Get(`schema_version`))
+	writeJSON(w, map[string][]string{`schema_ids`: ids})
+}
+
+func (a *Agent) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)[`id`]
+	// schemas can also be referred to by their sequence number on the ledger
+	if seqNo, err := strconv.Atoi(id); err == nil {
+		id = a.network.schemaBySeqNo(seqNo)
+	}
+
+	schema, ok := a.network.schema(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`schema %s not found on the ledger`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	writeJSON(w, map[string]domain.LedgerSchema{`schema`: schema})
+}
+
+func (a *Agent) handleCreateCredDef(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SchemaID          string `json:"schema_id"`
+		Tag               string `json:"tag"`
+		SupportRevocation bool   `json:"support_revocation"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Tag == `` {
+		req.Tag = `default`
+	}
+
+	def, err := a.network.publishCredDef(a.did, req.SchemaID, req.Tag)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	type sent struct {
+		CredDefID string `json:"credential_definition_id"`
+	}
+	writeJSON(w, struct {
+		sent
+		Sent sent `json:"sent"`
+	}{sent: sent{CredDefID: def.ID}, Sent: sent{CredDefID: def.ID}})
+}
+
+func (a *Agent) handleCreatedCredDefs(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if did := params.Get(`issuer_did`); did != `` && did != a.did {
+		writeJSON(w, map[string][]string{`credential_definition_ids`: {}})
+		return
+	}
+
+	ids := a.network.credDefsOf(a.did, params.Get(`schema_id`))
+	writeJSON(w, map[string][]string{`credential_definition_ids`: ids})
+}
+
+func (a *Agent) handleGetCredDef(w http.ResponseWriter, r *http.Request) {
+	def, ok := a.network.credDef(mux.Vars(r)[`id`])
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(`credential definition %s not found on the ledger`, mux.Vars(r)[`id`]))
+		return
+	}
+
+	schema, _ := a.network.schema(def.SchemaID)
+	var res struct {
+		CredDef struct {
+			Ver      string `json:"ver"`
+			ID       string `json:"id"`
+			SchemaID string `json:"schemaId"`
+			Type     string `json:"type"`
+			Tag      string `json:"tag"`
+		} `json:"credential_definition"`
+	}
+	res.CredDef.Ver, res.CredDef.ID, res.CredDef.SchemaID, res.CredDef.Type, res.CredDef.Tag = `1.0`, def.ID, strconv.Itoa(schema.SeqNo), `CL`, def.Tag
+	writeJSON(w, res)
+}
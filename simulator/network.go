@@ -0,0 +1,168 @@
+// Package simulator provides an in-process fake of the ACA-Py admin API so that the flows of the controller can be
+// exercised (eg: with httptest) without a running agent or ledger. Agents of a Network share a simulated ledger and
+// exchange protocol messages directly, while the state changes are delivered as webhooks to the URL of each agent.
+//
+// Only the endpoints used by the controller are supported: connections, schemas, credential definitions, the public
+// DID, issue-credential 2.0, present-proof 2.0, wallet credentials, out-of-band invitations and discover-features.
+// Cryptography is not simulated, hence proofs are verified against the credentials held by the prover.
+package simulator
+
+import (
+	"fmt"
+	"github.com/YasiruR/agent/domain"
+	"strings"
+	"sync"
+)
+
+// Network is the shared environment of simulated agents which holds the ledger and routes messages between them
+type Network struct {
+	mu          *sync.Mutex
+	seqNo       int
+	schemas     map[string]domain.LedgerSchema
+	credDefs    map[string]credDef
+	invitations map[string]invitationRef // invitation ID to inviter map
+}
+
+type credDef struct {
+	ID        string
+	SchemaID  string
+	IssuerDid string
+	Tag       string
+}
+
+type invitationRef struct {
+	agent  *Agent
+	connID string
+}
+
+func NewNetwork() *Network {
+	return &Network{
+		mu:          &sync.Mutex{},
+		schemas:     make(map[string]domain.LedgerSchema),
+		credDefs:    make(map[string]credDef),
+		invitations: make(map[string]invitationRef),
+	}
+}
+
+// NewAgent creates a simulated agent with a public DID on the ledger of the network. Webhooks are delivered once the
+// URL is set with SetWebhookURL.
+func (n *Network) NewAgent(label string) *Agent {
+	return newAgent(n, label)
+}
+
+func (n *Network) publishSchema(issuerDid string, s domain.Schema) (domain.LedgerSchema, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	id := fmt.Sprintf(`%s:2:%s:%s`, issuerDid, s.SchemaName, s.SchemaVersion)
+	if _, ok := n.schemas[id]; ok {
+		return domain.LedgerSchema{}, fmt.Errorf(`schema %s already exists on the ledger`, id)
+	}
+
+	n.seqNo++
+	schema := domain.LedgerSchema{Ver: `1.0`, ID: id, Name: s.SchemaName, Version: s.SchemaVersion, AttrNames: s.Attributes, SeqNo: n.seqNo}
+	n.schemas[id] = schema
+	return schema, nil
+}
+
+func (n *Network) schema(id string) (domain.LedgerSchema, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s, ok := n.schemas[id]
+	return s, ok
+}
+
+// schemasOf returns the IDs of schemas published by the DID, optionally filtered by name and version
+func (n *Network) schemasOf(issuerDid, name, version string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ids := []string{}
+	for id, s := range n.schemas {
+		if !strings.HasPrefix(id, issuerDid+`:`) {
+			continue
+		}
+		if (name != `` && s.Name != name) || (version != `` && s.Version != version) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// publishCredDef returns the existing credential definition if the issuer has already published one for the
+// schema with the same tag
+func (n *Network) publishCredDef(issuerDid, schemaID, tag string) (credDef, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	schema, ok := n.schemas[schemaID]
+	if !ok {
+		return credDef{}, fmt.Errorf(`schema %s does not exist on the ledger`, schemaID)
+	}
+
+	id := fmt.Sprintf(`%s:3:CL:%d:%s`, issuerDid, schema.SeqNo, tag)
+	if def, ok := n.credDefs[id]; ok {
+		return def, nil
+	}
+
+	n.seqNo++
+	def := credDef{ID: id, SchemaID: schemaID, IssuerDid: issuerDid, Tag: tag}
+	n.credDefs[id] = def
+	return def, nil
+}
+
+func (n *Network) credDef(id string) (credDef, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	def, ok := n.credDefs[id]
+	return def, ok
+}
+
+// credDefsOf returns the IDs of credential definitions published by the DID, optionally filtered by schema
+func (n *Network) credDefsOf(issuerDid, schemaID string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ids := []string{}
+	for id, def := range n.credDefs {
+		if def.IssuerDid != issuerDid || (schemaID != `` && def.SchemaID != schemaID) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func (n *Network) addInvitation(invID string, a *Agent, connID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.invitations[invID] = invitationRef{agent: a, connID: connID}
+}
+
+// takeInvitation resolves the inviter of a single use invitation
+func (n *Network) takeInvitation(invID string) (invitationRef, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ref, ok := n.invitations[invID]
+	delete(n.invitations, invID)
+	return ref, ok
+}
+
+func (n *Network) schemaBySeqNo(seqNo int) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for id, s := range n.schemas {
+		if s.SeqNo == seqNo {
+			return id
+		}
+	}
+
+	return ``
+}
@@ -0,0 +1,104 @@
+package simulator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// handleCreateOOBInvitation creates a connectionless invitation carrying the offers or requests given as attachments
+func (a *Agent) handleCreateOOBInvitation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Alias       string `json:"alias"`
+		MyLabel     string `json:"my_label"`
+		Attachments []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"attachments"`
+		HandshakeProtocols []string `json:"handshake_protocols"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	label := a.label
+	if req.MyLabel != `` {
+		label = req.MyLabel
+	}
+
+	type service struct {
+		ID              string   `json:"id"`
+		Type            string   `json:"type"`
+		RecipientKeys   []string `json:"recipientKeys"`
+		ServiceEndpoint string   `json:"serviceEndpoint"`
+	}
+
+	var inv struct {
+		ID                 string       `json:"@id"`
+		Type               string       `json:"@type"`
+		Label              string       `json:"label"`
+		HandshakeProtocols []string     `json:"handshake_protocols,omitempty"`
+		RequestsAttach     []attachment `json:"requests~attach,omitempty"`
+		Services           []service    `json:"services"`
+	}
+	inv.ID = newID()
+	inv.Type = `https://didcomm.org/out-of-band/1.1/invitation`
+	inv.Label = label
+	inv.HandshakeProtocols = req.HandshakeProtocols
+	inv.Services = []service{{ID: `#inline`, Type: `did-communication`, RecipientKeys: []string{`did:key:z` + base58(randomBytes(32))}, ServiceEndpoint: `sim://` + a.label}}
+
+	a.mu.Lock()
+	for _, att := range req.Attachments {
+		var msg interface{}
+		switch att.Type {
+		case `credential-offer`:
+			if rec, ok := a.credExs[att.ID]; ok {
+				msg = rec.CredOffer
+			}
+		case `present-proof`:
+			if rec, ok := a.presExs[att.ID]; ok {
+				msg = rec.PresRequest
+			}
+		}
+
+		if msg == nil {
+			a.mu.Unlock()
+			writeError(w, http.StatusBadRequest, fmt.Errorf(`no %s record found for attachment %s`, att.Type, att.ID))
+			return
+		}
+
+		data, err := json.Marshal(msg)
+		if err != nil {
+			a.mu.Unlock()
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		attached := attachment{ID: `request-0`, MimeType: `application/json`}
+		attached.Data.JSON = data
+		inv.RequestsAttach = append(inv.RequestsAttach, attached)
+	}
+	a.mu.Unlock()
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, struct {
+		InviMsgID     string          `json:"invi_msg_id"`
+		Invitation    json.RawMessage `json:"invitation"`
+		InvitationURL string          `json:"invitation_url"`
+		OobID         string          `json:"oob_id"`
+		State         string          `json:"state"`
+	}{
+		InviMsgID:     inv.ID,
+		Invitation:    data,
+		InvitationURL: `sim://` + a.label + `?oob=` + base64.URLEncoding.EncodeToString(data),
+		OobID:         newID(),
+		State:         `initial`,
+	})
+}
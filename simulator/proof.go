@@ -0,0 +1,906 @@
+package simulator
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/domain"
+	"github.com/gorilla/mux"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	topicPresentProof  = `present_proof_v2_0`
+	roleVerifier       = `verifier`
+	roleProver         = `prover`
+	formatIndyProofReq = `hlindy/proof-req@v2.0`
+	formatIndyProof    = `hlindy/proof@v2.0`
+)
+
+type presExRecord struct {
+	PresExID     string       `json:"pres_ex_id"`
+	ConnectionID string       `json:"connection_id,omitempty"`
+	ThreadID     string       `json:"thread_id"`
+	Role         string       `json:"role"`
+	Initiator    string       `json:"initiator"`
+	State        string       `json:"state"`
+	AutoPresent  bool         `json:"auto_present"`
+	AutoVerify   bool         `json:"auto_verify"`
+	Verified     string       `json:"verified,omitempty"`
+	VerifiedMsgs []string     `json:"verified_msgs,omitempty"`
+	PresRequest  *presMessage `json:"pres_request,omitempty"`
+	Pres         *presMessage `json:"pres,omitempty"`
+	ByFormat     presByFormat `json:"by_format"`
+	ErrorMsg     string       `json:"error_msg,omitempty"`
+	CreatedAt    string       `json:"created_at"`
+	UpdatedAt    string       `json:"updated_at"`
+	peer         *Agent
+	request      proofRequest
+	// sources are the credentials of the sub proofs which the verifier uses to evaluate the proof in place of the
+	// cryptographic verification
+	sources []walletCred
+}
+
+type presMessage struct {
+	ID                         string       `json:"@id"`
+	Type                       string       `json:"@type"`
+	Comment                    string       `json:"comment,omitempty"`
+	Formats                    []format     `json:"formats"`
+	RequestPresentationsAttach []attachment `json:"request_presentations~attach,omitempty"`
+	PresentationsAttach        []attachment `json:"presentations~attach,omitempty"`
+	Thread                     struct {
+		Thid string `json:"thid,omitempty"`
+	} `json:"~thread"`
+}
+
+type format struct {
+	AttachID string `json:"attach_id"`
+	Format   string `json:"format"`
+}
+
+type attachment struct {
+	ID       string `json:"@id"`
+	MimeType string `json:"mime-type"`
+	Data     struct {
+		Base64 string          `json:"base64,omitempty"`
+		JSON   json.RawMessage `json:"json,omitempty"`
+	} `json:"data"`
+}
+
+type presByFormat struct {
+	PresRequest *indyFormat `json:"pres_request,omitempty"`
+	Pres        *indyFormat `json:"pres,omitempty"`
+}
+
+type indyFormat struct {
+	Indy json.RawMessage `json:"indy,omitempty"`
+}
+
+type proofRequest struct {
+	Name                string                   `json:"name"`
+	Version             string                   `json:"version"`
+	Nonce               string                   `json:"nonce"`
+	RequestedAttributes map[string]requestedAttr `json:"requested_attributes"`
+	RequestedPredicates map[string]requestedPred `json:"requested_predicates"`
+	NonRevoked          json.RawMessage          `json:"non_revoked,omitempty"`
+}
+
+type requestedAttr struct {
+	Name         string              `json:"name,omitempty"`
+	Names        []string            `json:"names,omitempty"`
+	Restrictions []map[string]string `json:"restrictions,omitempty"`
+	NonRevoked   json.RawMessage     `json:"non_revoked,omitempty"`
+}
+
+type requestedPred struct {
+	Name         string              `json:"name"`
+	PType        string              `json:"p_type"`
+	PValue       int64               `json:"p_value"`
+	Restrictions []map[string]string `json:"restrictions,omitempty"`
+	NonRevoked   json.RawMessage     `json:"non_revoked,omitempty"`
+}
+
+type indyProof struct {
+	RequestedProof struct {
+		RevealedAttrs      map[string]revealedAttr  `json:"revealed_attrs"`
+		RevealedAttrGroups map[string]revealedGroup `json:"revealed_attr_groups"`
+		SelfAttestedAttrs  map[string]string        `json:"self_attested_attrs"`
+		UnrevealedAttrs    map[string]subProof      `json:"unrevealed_attrs"`
+		Predicates         map[string]subProof      `json:"predicates"`
+	} `json:"requested_proof"`
+	Identifiers []identifier `json:"identifiers"`
+}
+
+type revealedAttr struct {
+	SubProofIndex int    `json:"sub_proof_index"`
+	Raw           string `json:"raw"`
+	Encoded       string `json:"encoded"`
+}
+
+type revealedGroup struct {
+	SubProofIndex int                  `json:"sub_proof_index"`
+	Values        map[string]attrValue `json:"values"`
+}
+
+type attrValue struct {
+	Raw     string `json:"raw"`
+	Encoded string `json:"encoded"`
+}
+
+type subProof struct {
+	SubProofIndex int `json:"sub_proof_index"`
+}
+
+type identifier struct {
+	SchemaID  string  `json:"schema_id"`
+	CredDefID string  `json:"cred_def_id"`
+	RevRegID  *string `json:"rev_reg_id"`
+	Timestamp *int64  `json:"timestamp"`
+}
+
+// presentationSpec is the selection of credentials by the prover for each referent of the request
+type presentationSpec struct {
+	RequestedAttributes map[string]struct {
+		CredID   string `json:"cred_id"`
+		Revealed *bool  `json:"revealed"`
+	} `json:"requested_attributes"`
+	RequestedPredicates map[string]struct {
+		CredID string `json:"cred_id"`
+	} `json:"requested_predicates"`
+	SelfAttestedAttributes map[string]string `json:"self_attested_attributes"`
+}
+
+func (a *Agent) handleSendProofRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Comment             string `json:"comment"`
+		ConnectionID        string `json:"connection_id"`
+		AutoVerify          bool   `json:"auto_verify"`
+		PresentationRequest struct {
+			Indy *proofRequest `json:"indy"`
+		} `json:"presentation_request"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.PresentationRequest.Indy == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`only indy presentation requests are supported`))
+		return
+	}
+
+	peer, peerConnID, err := a.activeConn(req.ConnectionID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := a.newProofRequest(req.Comment, req.ConnectionID, req.AutoVerify, *req.PresentationRequest.Indy, peer)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	peer.receiveProofRequest(peerConnID, a, res)
+	writeJSON(w, res)
+}
+
+// handleCreateProofRequest creates a request which is not sent to any connection so that it can be attached to an
+// out-of-band invitation
+func (a *Agent) handleCreateProofRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Comment             string `json:"comment"`
+		AutoVerify          bool   `json:"auto_verify"`
+		PresentationRequest struct {
+			Indy *proofRequest `json:"indy"`
+		} `json:"presentation_request"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.PresentationRequest.Indy == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`only indy presentation requests are supported`))
+		return
+	}
+
+	res, err := a.newProofRequest(req.Comment, ``, req.AutoVerify, *req.PresentationRequest.Indy, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, res)
+}
+
+// newProofRequest stores a verifier record in the request-sent state with the request message and returns a copy
+func (a *Agent) newProofRequest(comment, connID string, autoVerify bool, pr proofRequest, peer *Agent) (presExRecord, error) {
+	if pr.Nonce == `` {
+		pr.Nonce = new(big.Int).SetBytes(randomBytes(10)).String()
+	}
+
+	data, err := json.Marshal(pr)
+	if err != nil {
+		return presExRecord{}, err
+	}
+
+	msg := &presMessage{
+		ID:      newID(),
+		Type:    `https://didcomm.org/present-proof/2.0/request-presentation`,
+		Comment: comment,
+		Formats: []format{{AttachID: `indy`, Format: formatIndyProofReq}},
+	}
+	att := attachment{ID: `indy`, MimeType: `application/json`}
+	att.Data.Base64 = base64.StdEncoding.EncodeToString(data)
+	msg.RequestPresentationsAttach = []attachment{att}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec := &presExRecord{
+		PresExID:     newID(),
+		ConnectionID: connID,
+		ThreadID:     msg.ID,
+		Role:         roleVerifier,
+		Initiator:    `self`,
+		State:        `request-sent`,
+		AutoVerify:   autoVerify,
+		PresRequest:  msg,
+		ByFormat:     presByFormat{PresRequest: &indyFormat{Indy: data}},
+		CreatedAt:    now(),
+		UpdatedAt:    now(),
+		peer:         peer,
+		request:      pr,
+	}
+	a.presExs[rec.PresExID] = rec
+	a.emit(topicPresentProof, rec)
+
+	return *rec, nil
+}
+
+func (a *Agent) receiveProofRequest(connID string, verifier *Agent, req presExRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec := &presExRecord{
+		PresExID:     newID(),
+		ConnectionID: connID,
+		ThreadID:     req.ThreadID,
+		Role:         roleProver,
+		Initiator:    `external`,
+		State:        `request-received`,
+		PresRequest:  req.PresRequest,
+		ByFormat:     presByFormat{PresRequest: req.ByFormat.PresRequest},
+		CreatedAt:    now(),
+		UpdatedAt:    now(),
+		peer:         verifier,
+		request:      req.request,
+	}
+	a.presExs[rec.PresExID] = rec
+	a.emit(topicPresentProof, rec)
+}
+
+func (a *Agent) handleSendPresentation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Indy *presentationSpec `json:"indy"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Indy == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`only indy presentations are supported`))
+		return
+	}
+
+	a.mu.Lock()
+	rec, err := a.presEx(mux.Vars(r)[`id`], roleProver, `request-received`)
+	if err != nil {
+		a.mu.Unlock()
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	proof, sources, err := a.buildProof(rec.request, *req.Indy)
+	if err != nil {
+		a.mu.Unlock()
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	data, err := json.Marshal(proof)
+	if err != nil {
+		a.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rec.Pres = &presMessage{ID: newID(), Type: `https://didcomm.org/present-proof/2.0/presentation`, Formats: []format{{AttachID: `indy`, Format: formatIndyProof}}}
+	rec.Pres.Thread.Thid = rec.ThreadID
+	att := attachment{ID: `indy`, MimeType: `application/json`}
+	att.Data.Base64 = base64.StdEncoding.EncodeToString(data)
+	rec.Pres.PresentationsAttach = []attachment{att}
+	rec.ByFormat.Pres = &indyFormat{Indy: data}
+	a.setPresState(rec, `presentation-sent`)
+	res := *rec
+	a.mu.Unlock()
+
+	res.peer.receivePresentation(res.ThreadID, *res.Pres, data, sources)
+	writeJSON(w, res)
+}
+
+// buildProof constructs the proof from the credentials selected for each referent where the lock should be held by
+// the caller. Similar to the agent, the presentation fails if a selected credential cannot satisfy the request.
+func (a *Agent) buildProof(req proofRequest, spec presentationSpec) (indyProof, []walletCred, error) {
+	var proof indyProof
+	proof.RequestedProof.RevealedAttrs = make(map[string]revealedAttr)
+	proof.RequestedProof.RevealedAttrGroups = make(map[string]revealedGroup)
+	proof.RequestedProof.SelfAttestedAttrs = make(map[string]string)
+	proof.RequestedProof.UnrevealedAttrs = make(map[string]subProof)
+	proof.RequestedProof.Predicates = make(map[string]subProof)
+
+	var sources []walletCred
+	indexes := make(map[string]int)
+	subProofOf := func(credID string) (walletCred, int, error) {
+		cred, ok := a.wallet[credID]
+		if !ok {
+			return walletCred{}, 0, fmt.Errorf(`credential %s not found in the wallet`, credID)
+		}
+
+		if i, ok := indexes[credID]; ok {
+			return cred, i, nil
+		}
+
+		indexes[credID] = len(sources)
+		sources = append(sources, cred)
+		proof.Identifiers = append(proof.Identifiers, identifier{SchemaID: cred.SchemaID, CredDefID: cred.CredDefID, RevRegID: cred.RevRegID})
+		return cred, indexes[credID], nil
+	}
+
+	for _, ref := range attrRefs(req.RequestedAttributes) {
+		attr := req.RequestedAttributes[ref]
+		if val, ok := spec.SelfAttestedAttributes[ref]; ok {
+			proof.RequestedProof.SelfAttestedAttrs[ref] = val
+			continue
+		}
+
+		sel, ok := spec.RequestedAttributes[ref]
+		if !ok {
+			return indyProof{}, nil, fmt.Errorf(`requested attribute %s is not provided`, ref)
+		}
+
+		cred, i, err := subProofOf(sel.CredID)
+		if err != nil {
+			return indyProof{}, nil, fmt.Errorf(`requested attribute %s - %v`, ref, err)
+		}
+
+		if len(attr.Names) > 0 {
+			group := revealedGroup{SubProofIndex: i, Values: make(map[string]attrValue)}
+			for _, name := range attr.Names {
+				val, ok := credValue(cred, name)
+				if !ok {
+					return indyProof{}, nil, fmt.Errorf(`credential %s does not contain attribute %s of %s`, cred.Referent, name, ref)
+				}
+				group.Values[name] = attrValue{Raw: val, Encoded: encode(val)}
+			}
+			proof.RequestedProof.RevealedAttrGroups[ref] = group
+			continue
+		}
+
+		val, ok := credValue(cred, attr.Name)
+		if !ok {
+			return indyProof{}, nil, fmt.Errorf(`credential %s does not contain attribute %s of %s`, cred.Referent, attr.Name, ref)
+		}
+
+		if sel.Revealed != nil && !*sel.Revealed {
+			proof.RequestedProof.UnrevealedAttrs[ref] = subProof{SubProofIndex: i}
+			continue
+		}
+		proof.RequestedProof.RevealedAttrs[ref] = revealedAttr{SubProofIndex: i, Raw: val, Encoded: encode(val)}
+	}
+
+	for _, ref := range predRefs(req.RequestedPredicates) {
+		pred := req.RequestedPredicates[ref]
+		sel, ok := spec.RequestedPredicates[ref]
+		if !ok {
+			return indyProof{}, nil, fmt.Errorf(`requested predicate %s is not provided`, ref)
+		}
+
+		cred, i, err := subProofOf(sel.CredID)
+		if err != nil {
+			return indyProof{}, nil, fmt.Errorf(`requested predicate %s - %v`, ref, err)
+		}
+
+		if err = satisfies(cred, pred); err != nil {
+			return indyProof{}, nil, fmt.Errorf(`requested predicate %s - %v`, ref, err)
+		}
+		proof.RequestedProof.Predicates[ref] = subProof{SubProofIndex: i}
+	}
+
+	return proof, sources, nil
+}
+
+func (a *Agent) receivePresentation(threadID string, msg presMessage, proof []byte, sources []walletCred) {
+	a.mu.Lock()
+	rec := a.presExByThread(threadID, roleVerifier)
+	if rec == nil {
+		a.mu.Unlock()
+		return
+	}
+
+	rec.Pres = &msg
+	rec.ByFormat.Pres = &indyFormat{Indy: proof}
+	rec.sources = sources
+	a.setPresState(rec, `presentation-received`)
+	id, auto := rec.PresExID, rec.AutoVerify
+	a.mu.Unlock()
+
+	if auto {
+		_, _ = a.verify(id)
+	}
+}
+
+func (a *Agent) handleVerifyPresentation(w http.ResponseWriter, r *http.Request) {
+	res, err := a.verify(mux.Vars(r)[`id`])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, res)
+}
+
+func (a *Agent) verify(presExID string) (presExRecord, error) {
+	a.mu.Lock()
+	rec, err := a.presEx(presExID, roleVerifier, `presentation-received`)
+	if err != nil {
+		a.mu.Unlock()
+		return presExRecord{}, err
+	}
+
+	var proof indyProof
+	if err = json.Unmarshal(rec.ByFormat.Pres.Indy, &proof); err != nil {
+		a.mu.Unlock()
+		return presExRecord{}, fmt.Errorf(`invalid presentation - %v`, err)
+	}
+
+	msgs := a.evaluateProof(rec.request, proof, rec.sources)
+	rec.Verified = strconv.FormatBool(len(msgs) == 0)
+	rec.VerifiedMsgs = msgs
+	a.setPresState(rec, stateDone)
+	res := *rec
+	a.mu.Unlock()
+
+	res.peer.receivePresAck(res.ThreadID)
+	return res, nil
+}
+
+// evaluateProof checks the proof against the request and returns the reasons for which it is not valid
+func (a *Agent) evaluateProof(req proofRequest, proof indyProof, sources []walletCred) []string {
+	var msgs []string
+	source := func(i int) (walletCred, bool) {
+		if i < 0 || i >= len(sources) || i >= len(proof.Identifiers) {
+			return walletCred{}, false
+		}
+		return sources[i], sources[i].CredDefID == proof.Identifiers[i].CredDefID
+	}
+
+	rp := proof.RequestedProof
+	for _, ref := range attrRefs(req.RequestedAttributes) {
+		attr := req.RequestedAttributes[ref]
+		if _, ok := rp.SelfAttestedAttrs[ref]; ok {
+			if len(attr.Restrictions) > 0 {
+				msgs = append(msgs, fmt.Sprintf(`%s: self-attested attribute does not satisfy restrictions`, ref))
+			}
+			continue
+		}
+
+		i, values, ok := -1, map[string]string{}, false
+		if v, found := rp.RevealedAttrs[ref]; found {
+			i, values[attr.Name], ok = v.SubProofIndex, v.Raw, true
+		} else if g, found := rp.RevealedAttrGroups[ref]; found {
+			i, ok = g.SubProofIndex, true
+			for name, v := range g.Values {
+				values[name] = v.Raw
+			}
+		} else if u, found := rp.UnrevealedAttrs[ref]; found {
+			i, ok = u.SubProofIndex, true
+		}
+
+		if !ok {
+			msgs = append(msgs, fmt.Sprintf(`%s: missing requested attribute`, ref))
+			continue
+		}
+
+		cred, ok := source(i)
+		if !ok {
+			msgs = append(msgs, fmt.Sprintf(`%s: invalid sub proof index %d`, ref, i))
+			continue
+		}
+
+		for name, raw := range values {
+			if val, found := credValue(cred, name); !found || val != raw {
+				msgs = append(msgs, fmt.Sprintf(`%s: revealed value of %s does not match the credential`, ref, name))
+			}
+		}
+
+		if !a.matchRestrictions(attr.Restrictions, cred) {
+			msgs = append(msgs, fmt.Sprintf(`%s: credential does not satisfy restrictions`, ref))
+		}
+	}
+
+	for _, ref := range predRefs(req.RequestedPredicates) {
+		pred := req.RequestedPredicates[ref]
+		p, found := rp.Predicates[ref]
+		if !found {
+			msgs = append(msgs, fmt.Sprintf(`%s: missing requested predicate`, ref))
+			continue
+		}
+
+		cred, ok := source(p.SubProofIndex)
+		if !ok {
+			msgs = append(msgs, fmt.Sprintf(`%s: invalid sub proof index %d`, ref, p.SubProofIndex))
+			continue
+		}
+
+		if err := satisfies(cred, pred); err != nil {
+			msgs = append(msgs, fmt.Sprintf(`%s: %v`, ref, err))
+		}
+
+		if !a.matchRestrictions(pred.Restrictions, cred) {
+			msgs = append(msgs, fmt.Sprintf(`%s: credential does not satisfy restrictions`, ref))
+		}
+	}
+
+	return msgs
+}
+
+// matchRestrictions checks if the credential satisfies any of the restrictions where all conditions of a
+// restriction should hold
+func (a *Agent) matchRestrictions(restrictions []map[string]string, cred walletCred) bool {
+	if len(restrictions) == 0 {
+		return true
+	}
+
+	schema, _ := a.network.schema(cred.SchemaID)
+	issuerDid := strings.SplitN(cred.CredDefID, `:`, 2)[0]
+	schemaIssuerDid := strings.SplitN(cred.SchemaID, `:`, 2)[0]
+
+restrictionLoop:
+	for _, restriction := range restrictions {
+		for key, want := range restriction {
+			var got string
+			switch key {
+			case `schema_id`:
+				got = cred.SchemaID
+			case `schema_issuer_did`:
+				got = schemaIssuerDid
+			case `schema_name`:
+				got = schema.Name
+			case `schema_version`:
+				got = schema.Version
+			case `issuer_did`:
+				got = issuerDid
+			case `cred_def_id`:
+				got = cred.CredDefID
+			case `rev_reg_id`:
+				if cred.RevRegID != nil {
+					got = *cred.RevRegID
+				}
+			default:
+				// attr::<name>::value and attr::<name>::marker
+				parts := strings.Split(key, `::`)
+				if len(parts) != 3 || parts[0] != `attr` {
+					continue restrictionLoop
+				}
+
+				val, ok := credValue(cred, parts[1])
+				switch {
+				case parts[2] == `value` && ok:
+					got = val
+				case parts[2] == `marker` && ok:
+					got = `1`
+				default:
+					continue restrictionLoop
+				}
+			}
+
+			if got != want {
+				continue restrictionLoop
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (a *Agent) receivePresAck(threadID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if rec := a.presExByThread(threadID, roleProver); rec != nil {
+		a.setPresState(rec, stateDone)
+	}
+}
+
+func (a *Agent) handlePresProblemReport(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Description string `json:"description"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	a.mu.Lock()
+	rec, err := a.presEx(mux.Vars(r)[`id`], ``, ``)
+	if err != nil {
+		a.mu.Unlock()
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	rec.ErrorMsg = req.Description
+	a.setPresState(rec, stateAbandoned)
+	peer, threadID, role := rec.peer, rec.ThreadID, rec.Role
+	a.mu.Unlock()
+
+	if peer != nil {
+		peer.receivePresProblemReport(threadID, oppositeRole(role), req.Description)
+	}
+	writeJSON(w, struct{}{})
+}
+
+func (a *Agent) receivePresProblemReport(threadID, role, description string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if rec := a.presExByThread(threadID, role); rec != nil {
+		rec.ErrorMsg = description
+		a.setPresState(rec, stateAbandoned)
+	}
+}
+
+func (a *Agent) handlePresRecords(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var list []*presExRecord
+	for _, rec := range a.presExs {
+		if (params.Get(`connection_id`) != `` && rec.ConnectionID != params.Get(`connection_id`)) ||
+			(params.Get(`role`) != `` && rec.Role != params.Get(`role`)) ||
+			(params.Get(`state`) != `` && rec.State != params.Get(`state`)) ||
+			(params.Get(`thread_id`) != `` && rec.ThreadID != params.Get(`thread_id`)) {
+			continue
+		}
+		list = append(list, rec)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt < list[j].CreatedAt })
+	writeJSON(w, map[string]interface{}{`results`: append([]*presExRecord{}, list...)})
+}
+
+func (a *Agent) handlePresRecord(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec, err := a.presEx(mux.Vars(r)[`id`], ``, ``)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeJSON(w, rec)
+}
+
+// handlePresCredentials lists the credentials of the wallet which can be used for the referents of the request, where
+// similar to the agent only the presence of attributes and the restrictions are checked but not predicate values
+func (a *Agent) handlePresCredentials(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	start, _ := strconv.Atoi(params.Get(`start`))
+	count, err := strconv.Atoi(params.Get(`count`))
+	if err != nil {
+		count = 10
+	}
+
+	filter := make(map[string]bool)
+	for _, ref := range strings.Split(params.Get(`referent`), `,`) {
+		if ref != `` {
+			filter[ref] = true
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec, err := a.presEx(mux.Vars(r)[`id`], roleProver, ``)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	var creds []walletCred
+	for _, c := range a.wallet {
+		creds = append(creds, c)
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Referent < creds[j].Referent })
+
+	type match struct {
+		CredInfo              walletCred `json:"cred_info"`
+		PresentationReferents []string   `json:"presentation_referents"`
+	}
+
+	matches := []match{}
+	for _, cred := range creds {
+		var refs []string
+		for _, ref := range attrRefs(rec.request.RequestedAttributes) {
+			attr := rec.request.RequestedAttributes[ref]
+			names := attr.Names
+			if len(names) == 0 {
+				names = []string{attr.Name}
+			}
+
+			held := true
+			for _, name := range names {
+				if _, ok := credValue(cred, name); !ok {
+					held = false
+				}
+			}
+
+			if held && (len(filter) == 0 || filter[ref]) && a.matchRestrictions(attr.Restrictions, cred) {
+				refs = append(refs, ref)
+			}
+		}
+
+		for _, ref := range predRefs(rec.request.RequestedPredicates) {
+			pred := rec.request.RequestedPredicates[ref]
+			if _, ok := credValue(cred, pred.Name); ok && (len(filter) == 0 || filter[ref]) && a.matchRestrictions(pred.Restrictions, cred) {
+				refs = append(refs, ref)
+			}
+		}
+
+		if len(refs) > 0 {
+			matches = append(matches, match{CredInfo: cred, PresentationReferents: refs})
+		}
+	}
+
+	results := []match{}
+	for i := start; i < len(matches) && i < start+count; i++ {
+		results = append(results, matches[i])
+	}
+
+	writeJSON(w, results)
+}
+
+func (a *Agent) handleDeletePresRecord(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec, err := a.presEx(mux.Vars(r)[`id`], ``, ``)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	delete(a.presExs, rec.PresExID)
+	deleted := *rec
+	deleted.State = stateDeleted
+	a.emit(topicPresentProof, deleted)
+	writeJSON(w, struct{}{})
+}
+
+// presEx returns the record of the exchange, which should be of the given role and state unless they are empty.
+// The lock should be held by the caller.
+func (a *Agent) presEx(presExID, role, state string) (*presExRecord, error) {
+	rec, ok := a.presExs[presExID]
+	if !ok {
+		return nil, fmt.Errorf(`presentation exchange record %s not found`, presExID)
+	}
+
+	if (role != `` && rec.Role != role) || (state != `` && rec.State != state) {
+		return nil, fmt.Errorf(`presentation exchange %s is in state %s as %s while %s is expected as %s`, presExID, rec.State, rec.Role, state, role)
+	}
+
+	return rec, nil
+}
+
+// presExByThread returns the record of the given role in the thread, where the lock should be held by the caller
+func (a *Agent) presExByThread(threadID, role string) *presExRecord {
+	for _, rec := range a.presExs {
+		if rec.ThreadID == threadID && rec.Role == role {
+			return rec
+		}
+	}
+
+	return nil
+}
+
+func (a *Agent) setPresState(rec *presExRecord, state string) {
+	rec.State = state
+	rec.UpdatedAt = now()
+	a.emit(topicPresentProof, rec)
+}
+
+// credValue returns the value of the attribute where names are compared in the canonical form as done by AnonCreds
+func credValue(cred walletCred, name string) (string, bool) {
+	for attr, val := range cred.Attrs {
+		if domain.CanonicalAttribute(attr) == domain.CanonicalAttribute(name) {
+			return val, true
+		}
+	}
+
+	return ``, false
+}
+
+func satisfies(cred walletCred, pred requestedPred) error {
+	raw, ok := credValue(cred, pred.Name)
+	if !ok {
+		return fmt.Errorf(`credential %s does not contain attribute %s`, cred.Referent, pred.Name)
+	}
+
+	val, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf(`attribute %s of credential %s is not an integer`, pred.Name, cred.Referent)
+	}
+
+	var holds bool
+	switch pred.PType {
+	case `>=`:
+		holds = val >= pred.PValue
+	case `>`:
+		holds = val > pred.PValue
+	case `<=`:
+		holds = val <= pred.PValue
+	case `<`:
+		holds = val < pred.PValue
+	default:
+		return fmt.Errorf(`unsupported predicate type %s`, pred.PType)
+	}
+
+	if !holds {
+		return fmt.Errorf(`predicate %s %s %d is not satisfied by credential %s`, pred.Name, pred.PType, pred.PValue, cred.Referent)
+	}
+
+	return nil
+}
+
+// encode encodes the raw value as done by AnonCreds, where 32-bit integers are kept as they are and other values are
+// encoded as the decimal of their SHA-256 hash
+func encode(raw string) string {
+	if i, err := strconv.ParseInt(raw, 10, 32); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+
+	sum := sha256.Sum256([]byte(raw))
+	return new(big.Int).SetBytes(sum[:]).String()
+}
+
+// attrRefs and predRefs return the referents in order so that proofs and messages are deterministic
+func attrRefs(m map[string]requestedAttr) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func predRefs(m map[string]requestedPred) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -3,9 +3,14 @@ package requests
 import "github.com/YasiruR/agent/domain"
 
 type Offer struct {
-	AutoProcess bool                     `json:"auto_process"`
-	CredPreview domain.CredentialPreview `json:"credential_preview"`
-	Filter      struct {
+	AutoProcess bool              `json:"auto_process"`
+	Template    string            `json:"template"`
+	Values      map[string]string `json:"values"`
+	AutoRemove  *bool             `json:"auto_remove"`
+	// RetentionDays overrides the global retention of the exchange record once the protocol completes
+	RetentionDays *int                     `json:"retention_days"`
+	CredPreview   domain.CredentialPreview `json:"credential_preview"`
+	Filter        struct {
 		Indy domain.IndySchemaMeta `json:"indy"`
 	} `json:"filter"`
 }
@@ -3,5 +3,9 @@ package requests
 import "github.com/YasiruR/agent/domain"
 
 type ProofReq struct {
-	PresentReq domain.PresentationRequest `json:"presentation_request"`
+	Template    string                     `json:"template"`
+	Values      map[string]interface{}     `json:"values"`
+	PresentReq  domain.PresentationRequest `json:"presentation_request"`
+	AutoVerify  bool                       `json:"auto_verify"`
+	CallbackURL string                     `json:"callback_url"`
 }
@@ -1,48 +1,120 @@
 package agent
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/YasiruR/agent/agent"
+	"github.com/YasiruR/agent/agent/events"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/domain"
 	"github.com/YasiruR/agent/transport/agent/requests"
 	"github.com/gorilla/mux"
 	"github.com/tryfix/log"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Server struct {
-	port   int
-	router *mux.Router
-	agent  *agent.Agent
-	logger log.Logger
+	port    int
+	router  *mux.Router
+	agent   *agent.Agent
+	events  *events.Broker
+	origins []string // origins allowed to open event websockets in addition to the host of the controller
+	logger  log.Logger
 }
 
-func New(port int, agent *agent.Agent, logger log.Logger) *Server {
-	return &Server{port: port, router: mux.NewRouter(), agent: agent, logger: logger}
-}
-
-func (s *Server) Serve() {
+func New(port int, agent *agent.Agent, broker *events.Broker, origins []string, logger log.Logger) *Server {
+	s := &Server{port: port, router: mux.NewRouter(), agent: agent, events: broker, origins: origins, logger: logger}
 	s.router.HandleFunc(`/invitation/create`, s.handleCreateInvitation).Methods(http.MethodPost)
 	s.router.HandleFunc(`/invitation/accept`, s.handleAcceptInvitation).Methods(http.MethodPost)
 
 	s.router.HandleFunc(`/connection/{id}`, s.handleGetConnection).Methods(http.MethodGet)
 	s.router.HandleFunc(`/connection/accept-request/{their_label}`, s.handleAcceptRequest).Methods(http.MethodPost)
+	s.router.HandleFunc(`/connection/protocol/{their_label}`, s.handleGetProtocols).Methods(http.MethodGet)
+	s.router.HandleFunc(`/connection/protocol/{their_label}`, s.handleSetProtocols).Methods(http.MethodPost)
+	s.router.HandleFunc(`/connection/discover/{their_label}`, s.handleDiscoverProtocols).Methods(http.MethodPost)
+
+	s.router.HandleFunc(`/endorser`, s.handleGetEndorser).Methods(http.MethodGet)
+	s.router.HandleFunc(`/endorser/{their_label}`, s.handleSetEndorser).Methods(http.MethodPost)
+	s.router.HandleFunc(`/transaction`, s.handleGetTransactions).Methods(http.MethodGet)
+	s.router.HandleFunc(`/transaction/{id}`, s.handleGetTransaction).Methods(http.MethodGet)
+	s.router.HandleFunc(`/endorsement/author/{their_label}`, s.handleSetAuthor).Methods(http.MethodPost)
+	s.router.HandleFunc(`/endorsement/request`, s.handleGetEndorsementRequests).Methods(http.MethodGet)
+	s.router.HandleFunc(`/endorsement/request/{id}`, s.handleGetEndorsementRequest).Methods(http.MethodGet)
+	s.router.HandleFunc(`/endorsement/request/{id}/endorse`, s.handleEndorse).Methods(http.MethodPost)
+	s.router.HandleFunc(`/endorsement/request/{id}/refuse`, s.handleRefuse).Methods(http.MethodPost)
+	s.router.HandleFunc(`/endorsement/policy`, s.handleGetEndorsementPolicy).Methods(http.MethodGet)
+	s.router.HandleFunc(`/endorsement/policy`, s.handleSetEndorsementPolicy).Methods(http.MethodPut)
 
 	s.router.HandleFunc(`/schema/create`, s.handleCreateSchema).Methods(http.MethodPost)
+	s.router.HandleFunc(`/schemas`, s.handleGetSchemas).Methods(http.MethodGet)
+	s.router.HandleFunc(`/schemas/{id}`, s.handleGetSchema).Methods(http.MethodGet)
+	s.router.HandleFunc(`/schema/ensure`, s.handleEnsureSchema).Methods(http.MethodPost)
+	s.router.HandleFunc(`/schema/evolve`, s.handleEvolveSchema).Methods(http.MethodPost)
+	s.router.HandleFunc(`/schema/families`, s.handleGetSchemaFamilies).Methods(http.MethodGet)
+	s.router.HandleFunc(`/schema/family/{name}`, s.handleGetSchemaFamily).Methods(http.MethodGet)
 	s.router.HandleFunc(`/credential-definition/create`, s.handleCreateCredentialDef).Methods(http.MethodPost)
+	s.router.HandleFunc(`/credential-definition/ensure`, s.handleEnsureCredentialDef).Methods(http.MethodPost)
+	s.router.HandleFunc(`/credential-definition/bootstrapped`, s.handleGetBootstrapped).Methods(http.MethodGet)
 
+	s.router.HandleFunc(`/credential/template`, s.handleCreateOfferTemplate).Methods(http.MethodPost)
+	s.router.HandleFunc(`/credential/template`, s.handleGetOfferTemplates).Methods(http.MethodGet)
+	s.router.HandleFunc(`/credential/template/{name}`, s.handleGetOfferTemplate).Methods(http.MethodGet)
+	s.router.HandleFunc(`/credential/template/{name}`, s.handleUpdateOfferTemplate).Methods(http.MethodPut)
+	s.router.HandleFunc(`/credential/template/{name}`, s.handleDeleteOfferTemplate).Methods(http.MethodDelete)
+
+	s.router.HandleFunc(`/credential/offer-oob`, s.handleCreateConnectionlessOffer).Methods(http.MethodPost)
+	s.router.HandleFunc(`/credential/offer-oob/{thread_id}`, s.handleGetConnectionlessExchange).Methods(http.MethodGet)
+	s.router.HandleFunc(`/credential/offer-oob/{thread_id}/qr`, s.handleGetConnectionlessQR).Methods(http.MethodGet)
+	s.router.HandleFunc(`/credential/exchanges`, s.handleGetPendingExchanges(models.ExchangeTypeCredential)).Methods(http.MethodGet)
+	s.router.HandleFunc(`/credential/exchange/{id}`, s.handleGetPendingExchange).Methods(http.MethodGet)
 	s.router.HandleFunc(`/credential/record/{from}`, s.handleGetCredRecord).Methods(http.MethodGet)
 	s.router.HandleFunc(`/credential/offer/{receiver}`, s.handleSendOffer).Methods(http.MethodPost)
 	s.router.HandleFunc(`/credential/request/{id}`, s.handleRequestCredential).Methods(http.MethodPost)
 	s.router.HandleFunc(`/credential/issue/{id}`, s.handleIssueCredential).Methods(http.MethodPost)
 	s.router.HandleFunc(`/credential/store/{id}`, s.handleStoreCredential).Methods(http.MethodPost)
 
+	s.router.HandleFunc(`/proof/exchanges`, s.handleGetPendingExchanges(models.ExchangeTypeProof)).Methods(http.MethodGet)
+	s.router.HandleFunc(`/proof/exchange/{id}`, s.handleGetPendingExchange).Methods(http.MethodGet)
 	s.router.HandleFunc(`/proof/request/{receiver}`, s.handleSendProofReq).Methods(http.MethodPost)
+	s.router.HandleFunc(`/proof/request-oob`, s.handleCreateConnectionlessProofReq).Methods(http.MethodPost)
+	s.router.HandleFunc(`/proof/request-oob/{thread_id}`, s.handleGetConnectionlessExchange).Methods(http.MethodGet)
+	s.router.HandleFunc(`/proof/request-oob/{thread_id}/qr`, s.handleGetConnectionlessQR).Methods(http.MethodGet)
+	s.router.HandleFunc(`/proof/template`, s.handleCreateProofTemplate).Methods(http.MethodPost)
+	s.router.HandleFunc(`/proof/template`, s.handleGetProofTemplates).Methods(http.MethodGet)
+	s.router.HandleFunc(`/proof/template/{name}`, s.handleGetProofTemplate).Methods(http.MethodGet)
+	s.router.HandleFunc(`/proof/template/{name}`, s.handleUpdateProofTemplate).Methods(http.MethodPut)
+	s.router.HandleFunc(`/proof/template/{name}`, s.handleDeleteProofTemplate).Methods(http.MethodDelete)
 	s.router.HandleFunc(`/proof/present/{receiver}`, s.handlePresentProof).Methods(http.MethodPost)
+	s.router.HandleFunc(`/proof/preview/{receiver}`, s.handlePreviewProof).Methods(http.MethodPost)
 	s.router.HandleFunc(`/proof/verify/{id}`, s.handleVerifyProof).Methods(http.MethodPost)
+	s.router.HandleFunc(`/proof/verification/{id}`, s.handleGetVerification).Methods(http.MethodGet)
+	s.router.HandleFunc(`/proof/selection`, s.handleGetSelectionPolicy).Methods(http.MethodGet)
+	s.router.HandleFunc(`/proof/selection`, s.handleSetSelectionPolicy).Methods(http.MethodPut)
+	s.router.HandleFunc(`/proof/{pres_ex_id}/candidates`, s.handleGetCandidates).Methods(http.MethodGet)
+
+	s.router.HandleFunc(`/events`, s.handleEventStream).Methods(http.MethodGet)
+	s.router.HandleFunc(`/events/ws`, s.handleEventSocket).Methods(http.MethodGet)
+
+	s.router.HandleFunc(`/audit`, s.handleGetAuditEntries).Methods(http.MethodGet)
+	s.router.HandleFunc(`/audit/export`, s.handleExportAuditEntries).Methods(http.MethodGet)
+	s.router.HandleFunc(`/audit/verify`, s.handleVerifyAuditLedger).Methods(http.MethodGet)
+
+	return s
+}
+
+// Handler returns the router of the controller endpoints so that the server can also be mounted elsewhere (eg: httptest)
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
 
+func (s *Server) Serve() {
 	s.logger.Info(fmt.Sprintf("controller started listening on %d", s.port))
 	if err := http.ListenAndServe(":"+strconv.Itoa(s.port), s.router); err != nil {
 		s.logger.Fatal(err)
@@ -111,7 +183,18 @@ func (s *Server) handleGetConnection(w http.ResponseWriter, r *http.Request) {
 	s.writeResponse(res, w)
 }
 
-func (s *Server) handleCreateSchema(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleGetProtocols(w http.ResponseWriter, r *http.Request) {
+	versions, err := s.agent.ProtocolVersions(mux.Vars(r)[`their_label`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get protocol versions - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(versions, w)
+}
+
+func (s *Server) handleSetProtocols(w http.ResponseWriter, r *http.Request) {
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		s.logger.Error(err)
@@ -120,9 +203,28 @@ func (s *Server) handleCreateSchema(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	res, err := s.agent.CreateSchema(data)
+	var req models.ProtocolVersions
+	err = json.Unmarshal(data, &req)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf(`create schema - %v`, err))
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	versions, err := s.agent.SetProtocolVersions(mux.Vars(r)[`their_label`], req)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`set protocol versions - %v`, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.writeJSON(versions, w)
+}
+
+func (s *Server) handleDiscoverProtocols(w http.ResponseWriter, r *http.Request) {
+	res, err := s.agent.DiscoverProtocols(mux.Vars(r)[`their_label`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`discover protocols - %v`, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -130,7 +232,7 @@ func (s *Server) handleCreateSchema(w http.ResponseWriter, r *http.Request) {
 	s.writeResponse(res, w)
 }
 
-func (s *Server) handleCreateCredentialDef(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleSetEndorser(w http.ResponseWriter, r *http.Request) {
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		s.logger.Error(err)
@@ -139,18 +241,106 @@ func (s *Server) handleCreateCredentialDef(w http.ResponseWriter, r *http.Reques
 	}
 	defer r.Body.Close()
 
-	res, err := s.agent.CreateCredentialDef(data)
+	var req models.Endorser
+	err = json.Unmarshal(data, &req)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf(`create schema - %v`, err))
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e, err := s.agent.SetEndorser(mux.Vars(r)[`their_label`], req.Did, req.Name)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`set endorser - %v`, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	s.writeResponse(res, w)
+	s.writeJSON(e, w)
 }
 
-func (s *Server) handleSendOffer(w http.ResponseWriter, r *http.Request) {
-	receiver := mux.Vars(r)[`receiver`]
+func (s *Server) handleGetEndorser(w http.ResponseWriter, _ *http.Request) {
+	e, ok := s.agent.Endorser()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(e, w)
+}
+
+func (s *Server) handleGetTransactions(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(s.agent.Transactions(), w)
+}
+
+func (s *Server) handleGetTransaction(w http.ResponseWriter, r *http.Request) {
+	txn, err := s.agent.Transaction(mux.Vars(r)[`id`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get transaction - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(txn, w)
+}
+
+func (s *Server) handleSetAuthor(w http.ResponseWriter, r *http.Request) {
+	if err := s.agent.SetAuthor(mux.Vars(r)[`their_label`]); err != nil {
+		s.logger.Error(fmt.Sprintf(`set author - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleGetEndorsementRequests(w http.ResponseWriter, _ *http.Request) {
+	list, err := s.agent.EndorsementRequests()
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get endorsement requests - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(list, w)
+}
+
+func (s *Server) handleGetEndorsementRequest(w http.ResponseWriter, r *http.Request) {
+	req, err := s.agent.EndorsementRequest(mux.Vars(r)[`id`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get endorsement request - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(req, w)
+}
+
+func (s *Server) handleEndorse(w http.ResponseWriter, r *http.Request) {
+	if err := s.agent.Endorse(mux.Vars(r)[`id`]); err != nil {
+		s.logger.Error(fmt.Sprintf(`endorse - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleRefuse(w http.ResponseWriter, r *http.Request) {
+	if err := s.agent.Refuse(mux.Vars(r)[`id`]); err != nil {
+		s.logger.Error(fmt.Sprintf(`refuse - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleGetEndorsementPolicy(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(s.agent.EndorsementPolicy(), w)
+}
+
+func (s *Server) handleSetEndorsementPolicy(w http.ResponseWriter, r *http.Request) {
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		s.logger.Error(err)
@@ -159,28 +349,49 @@ func (s *Server) handleSendOffer(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	var req requests.Offer
+	var req models.EndorsementPolicy
 	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = req.Validate(); err != nil {
+		s.logger.Error(fmt.Sprintf(`invalid endorsement policy - %v`, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = s.agent.SetEndorsementPolicy(req); err != nil {
+		s.logger.Error(fmt.Sprintf(`set endorsement policy - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(req, w)
+}
+
+func (s *Server) handleCreateSchema(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		s.logger.Error(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer r.Body.Close()
 
-	if req.AutoProcess == true {
-		res, err := s.agent.SendCredentialAuto(req.CredPreview, req.Filter.Indy, receiver)
-		if err != nil {
-			s.logger.Error(fmt.Sprintf(`send offer - %v`, err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		s.writeResponse(res, w)
+	var schema domain.Schema
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	res, err := s.agent.SendCredentialOffer(req.CredPreview, req.Filter.Indy, receiver)
+	res, err := s.agent.CreateSchema(schema)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf(`send offer - %v`, err))
+		s.logger.Error(fmt.Sprintf(`create schema - %v`, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -188,35 +399,41 @@ func (s *Server) handleSendOffer(w http.ResponseWriter, r *http.Request) {
 	s.writeResponse(res, w)
 }
 
-func (s *Server) handleGetCredRecord(w http.ResponseWriter, r *http.Request) {
-	from := mux.Vars(r)[`from`]
-	res, err := s.agent.CredentialRecord(from)
+// handleGetSchemas lists the IDs of schemas created by this agent filtered by optional name and version
+func (s *Server) handleGetSchemas(w http.ResponseWriter, r *http.Request) {
+	ids, err := s.agent.CreatedSchemas(r.URL.Query().Get(`schema_name`), r.URL.Query().Get(`schema_version`))
 	if err != nil {
-		s.logger.Error(fmt.Errorf(`fetch credential record - %v`, err))
+		s.logger.Error(fmt.Sprintf(`get schemas - %v`, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	s.writeResponse(res, w)
+	s.writeJSON(map[string][]string{`schema_ids`: ids}, w)
 }
 
-func (s *Server) handleRequestCredential(w http.ResponseWriter, r *http.Request) {
-	credExID := mux.Vars(r)[`id`]
-	res, err := s.agent.RequestCredential(credExID)
+func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	schema, err := s.agent.Schema(mux.Vars(r)[`id`])
 	if err != nil {
-		s.logger.Error(fmt.Errorf(`request credential - %v`, err))
-		w.WriteHeader(http.StatusInternalServerError)
+		s.logger.Error(fmt.Sprintf(`get schema - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	s.writeResponse(res, w)
+	s.writeJSON(schema, w)
 }
 
-func (s *Server) handleIssueCredential(w http.ResponseWriter, r *http.Request) {
-	credExID := mux.Vars(r)[`id`]
-	res, err := s.agent.IssueCredential(credExID)
+func (s *Server) handleCreateCredentialDef(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		s.logger.Error(fmt.Errorf(`issue credential - %v`, err))
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	res, err := s.agent.CreateCredentialDef(data)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`create schema - %v`, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -224,20 +441,35 @@ func (s *Server) handleIssueCredential(w http.ResponseWriter, r *http.Request) {
 	s.writeResponse(res, w)
 }
 
-func (s *Server) handleStoreCredential(w http.ResponseWriter, r *http.Request) {
-	credExID := mux.Vars(r)[`id`]
-	res, err := s.agent.StoreCredential(credExID)
+func (s *Server) handleEnsureSchema(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		s.logger.Error(fmt.Errorf(`store credential - %v`, err))
+		s.logger.Error(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer r.Body.Close()
 
-	s.writeResponse(res, w)
+	var schema domain.Schema
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	id, created, err := s.agent.EnsureSchema(schema)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`ensure schema - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(map[string]interface{}{`schema_id`: id, `created`: created}, w)
 }
 
-func (s *Server) handleSendProofReq(w http.ResponseWriter, r *http.Request) {
-	receiver := mux.Vars(r)[`receiver`]
+// handleEvolveSchema publishes the next version of a schema with the attribute changes and a credential definition
+func (s *Server) handleEvolveSchema(w http.ResponseWriter, r *http.Request) {
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		s.logger.Error(err)
@@ -246,45 +478,750 @@ func (s *Server) handleSendProofReq(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	var req requests.ProofReq
-	err = json.Unmarshal(data, &req)
+	var e domain.SchemaEvolution
+	err = json.Unmarshal(data, &e)
 	if err != nil {
 		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.agent.EvolveSchema(e)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`evolve schema - %v`, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	res, err := s.agent.SendProofRequest(req.PresentReq, receiver)
+	s.writeJSON(res, w)
+}
+
+func (s *Server) handleGetSchemaFamilies(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(s.agent.SchemaFamilies(), w)
+}
+
+func (s *Server) handleGetSchemaFamily(w http.ResponseWriter, r *http.Request) {
+	family, err := s.agent.SchemaFamily(mux.Vars(r)[`name`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get schema family - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(family, w)
+}
+
+func (s *Server) handleEnsureCredentialDef(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		s.logger.Error(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer r.Body.Close()
 
-	s.writeResponse(res, w)
+	var setup domain.CredentialDefinitionSetup
+	err = json.Unmarshal(data, &setup)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.agent.EnsureCredentialDef(setup)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`ensure credential definition - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(res, w)
 }
 
-func (s *Server) handlePresentProof(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleGetBootstrapped(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(s.agent.Bootstrapped(), w)
+}
+
+func (s *Server) handleSendOffer(w http.ResponseWriter, r *http.Request) {
 	receiver := mux.Vars(r)[`receiver`]
-	res, err := s.agent.PresentProof(receiver)
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var req requests.Offer
+	err = json.Unmarshal(data, &req)
 	if err != nil {
 		s.logger.Error(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	opts := models.OfferOptions{AutoRemove: req.AutoRemove}
+	if req.RetentionDays != nil {
+		retention := time.Duration(*req.RetentionDays) * 24 * time.Hour
+		opts.Retention = &retention
+	}
+
+	if req.Template != `` {
+		res, err := s.agent.SendTemplateOffer(req.Template, req.Values, receiver, req.AutoProcess, opts)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf(`send template offer - %v`, err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.writeResponse(res, w)
+		return
+	}
+
+	if req.AutoProcess == true {
+		res, err := s.agent.SendCredentialAuto(req.CredPreview, req.Filter.Indy, receiver, opts)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf(`send offer - %v`, err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.writeResponse(res, w)
+		return
+	}
+
+	res, err := s.agent.SendCredentialOffer(req.CredPreview, req.Filter.Indy, receiver, opts)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`send offer - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	s.writeResponse(res, w)
 }
 
-func (s *Server) handleVerifyProof(w http.ResponseWriter, r *http.Request) {
-	presExID := mux.Vars(r)[`id`]
-	res, err := s.agent.VerifyProof(presExID)
+func (s *Server) handleCreateOfferTemplate(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var t domain.OfferTemplate
+	err = json.Unmarshal(data, &t)
 	if err != nil {
 		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = s.agent.CreateOfferTemplate(t); err != nil {
+		s.logger.Error(fmt.Sprintf(`create offer template - %v`, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.writeJSON(t, w)
+}
+
+func (s *Server) handleGetOfferTemplates(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(s.agent.OfferTemplates(), w)
+}
+
+func (s *Server) handleGetOfferTemplate(w http.ResponseWriter, r *http.Request) {
+	t, err := s.agent.OfferTemplate(mux.Vars(r)[`name`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get offer template - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(t, w)
+}
+
+func (s *Server) handleUpdateOfferTemplate(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var t domain.OfferTemplate
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	t.Name = mux.Vars(r)[`name`]
+
+	if err = s.agent.UpdateOfferTemplate(t); err != nil {
+		s.logger.Error(fmt.Sprintf(`update offer template - %v`, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.writeJSON(t, w)
+}
+
+func (s *Server) handleDeleteOfferTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := s.agent.DeleteOfferTemplate(mux.Vars(r)[`name`]); err != nil {
+		s.logger.Error(fmt.Sprintf(`delete offer template - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCreateConnectionlessOffer(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var req requests.Offer
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	opts := models.OfferOptions{AutoRemove: req.AutoRemove}
+	if req.RetentionDays != nil {
+		retention := time.Duration(*req.RetentionDays) * 24 * time.Hour
+		opts.Retention = &retention
+	}
+
+	var ex models.ConnectionlessExchange
+	if req.Template != `` {
+		ex, err = s.agent.CreateConnectionlessTemplateOffer(req.Template, req.Values, opts)
+	} else {
+		ex, err = s.agent.CreateConnectionlessOffer(req.CredPreview, req.Filter.Indy, opts)
+	}
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`create connectionless offer - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(ex, w)
+}
+
+func (s *Server) handleGetConnectionlessExchange(w http.ResponseWriter, r *http.Request) {
+	ex, err := s.agent.ConnectionlessExchange(mux.Vars(r)[`thread_id`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get connectionless exchange - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(ex, w)
+}
+
+func (s *Server) handleGetConnectionlessQR(w http.ResponseWriter, r *http.Request) {
+	threadID := mux.Vars(r)[`thread_id`]
+	if _, err := s.agent.ConnectionlessExchange(threadID); err != nil {
+		s.logger.Error(fmt.Sprintf(`get connectionless QR code - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	qr, err := s.agent.ConnectionlessQR(threadID)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get connectionless QR code - %v`, err))
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `image/png`)
+	s.writeResponse(qr, w)
+}
+
+// handleGetPendingExchanges lists the exchanges of the given type which are yet to complete along with their expiry
+func (s *Server) handleGetPendingExchanges(exType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s.writeJSON(s.agent.PendingExchanges(exType), w)
+	}
+}
+
+func (s *Server) handleGetPendingExchange(w http.ResponseWriter, r *http.Request) {
+	ex, err := s.agent.PendingExchange(mux.Vars(r)[`id`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get pending exchange - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(ex, w)
+}
+
+func (s *Server) handleGetCredRecord(w http.ResponseWriter, r *http.Request) {
+	from := mux.Vars(r)[`from`]
+	res, err := s.agent.CredentialRecord(from)
+	if err != nil {
+		s.logger.Error(fmt.Errorf(`fetch credential record - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeResponse(res, w)
+}
+
+func (s *Server) handleRequestCredential(w http.ResponseWriter, r *http.Request) {
+	credExID := mux.Vars(r)[`id`]
+	res, err := s.agent.RequestCredential(credExID)
+	if err != nil {
+		s.logger.Error(fmt.Errorf(`request credential - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeResponse(res, w)
+}
+
+func (s *Server) handleIssueCredential(w http.ResponseWriter, r *http.Request) {
+	credExID := mux.Vars(r)[`id`]
+	res, err := s.agent.IssueCredential(credExID)
+	if err != nil {
+		s.logger.Error(fmt.Errorf(`issue credential - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeResponse(res, w)
+}
+
+func (s *Server) handleStoreCredential(w http.ResponseWriter, r *http.Request) {
+	credExID := mux.Vars(r)[`id`]
+	res, err := s.agent.StoreCredential(credExID)
+	if err != nil {
+		s.logger.Error(fmt.Errorf(`store credential - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeResponse(res, w)
+}
+
+func (s *Server) handleSendProofReq(w http.ResponseWriter, r *http.Request) {
+	receiver := mux.Vars(r)[`receiver`]
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var req requests.ProofReq
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if req.Template != `` && (req.PresentReq.Indy != nil || req.PresentReq.Dif != nil) {
+		s.logger.Error(`proof request should either refer to a template or contain a presentation request`)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	opts := models.ProofRequestOptions{AutoVerify: req.AutoVerify, CallbackURL: req.CallbackURL}
+	if req.Template != `` {
+		res, err := s.agent.SendTemplateProofRequest(req.Template, req.Values, receiver, opts)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf(`send template proof request - %v`, err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.writeResponse(res, w)
+		return
+	}
+
+	res, err := s.agent.SendProofRequest(req.PresentReq, receiver, opts)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeResponse(res, w)
+}
+
+func (s *Server) handleCreateConnectionlessProofReq(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var req requests.ProofReq
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if req.Template != `` && (req.PresentReq.Indy != nil || req.PresentReq.Dif != nil) {
+		s.logger.Error(`proof request should either refer to a template or contain a presentation request`)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	opts := models.ProofRequestOptions{AutoVerify: req.AutoVerify, CallbackURL: req.CallbackURL}
+	var ex models.ConnectionlessExchange
+	if req.Template != `` {
+		ex, err = s.agent.CreateConnectionlessTemplateProofRequest(req.Template, req.Values, opts)
+	} else {
+		ex, err = s.agent.CreateConnectionlessProofRequest(req.PresentReq, opts)
+	}
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`create connectionless proof request - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(ex, w)
+}
+
+func (s *Server) handleCreateProofTemplate(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var t domain.ProofTemplate
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = s.agent.CreateProofTemplate(t); err != nil {
+		s.logger.Error(fmt.Sprintf(`create proof template - %v`, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.writeJSON(t, w)
+}
+
+func (s *Server) handleGetProofTemplates(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(s.agent.ProofTemplates(), w)
+}
+
+func (s *Server) handleGetProofTemplate(w http.ResponseWriter, r *http.Request) {
+	t, err := s.agent.ProofTemplate(mux.Vars(r)[`name`])
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get proof template - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.writeJSON(t, w)
+}
+
+func (s *Server) handleUpdateProofTemplate(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var t domain.ProofTemplate
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	t.Name = mux.Vars(r)[`name`]
+
+	if err = s.agent.UpdateProofTemplate(t); err != nil {
+		s.logger.Error(fmt.Sprintf(`update proof template - %v`, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.writeJSON(t, w)
+}
+
+func (s *Server) handleDeleteProofTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := s.agent.DeleteProofTemplate(mux.Vars(r)[`name`]); err != nil {
+		s.logger.Error(fmt.Sprintf(`delete proof template - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePresentProof(w http.ResponseWriter, r *http.Request) {
+	pres, err := readPresentation(r)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.agent.PresentProof(mux.Vars(r)[`receiver`], pres)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeResponse(res, w)
+}
+
+// handlePreviewProof shows what the verifier would learn from the presentation without sending it
+func (s *Server) handlePreviewProof(w http.ResponseWriter, r *http.Request) {
+	pres, err := readPresentation(r)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.agent.PreviewProof(mux.Vars(r)[`receiver`], pres)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`preview proof - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(res, w)
+}
+
+// readPresentation decodes the inputs of the holder to a presentation where the body is optional
+func readPresentation(r *http.Request) (domain.Presentation, error) {
+	var pres domain.Presentation
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return pres, err
+	}
+	defer r.Body.Close()
+
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &pres); err != nil {
+			return pres, err
+		}
+	}
+
+	return pres, nil
+}
+
+func (s *Server) handleVerifyProof(w http.ResponseWriter, r *http.Request) {
+	presExID := mux.Vars(r)[`id`]
+	res, err := s.agent.VerifyProof(presExID)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(res, w)
+}
+
+// handleGetVerification returns the stored outcome of verifying the presentation of an exchange
+func (s *Server) handleGetVerification(w http.ResponseWriter, r *http.Request) {
+	presExID := mux.Vars(r)[`id`]
+	rec, ok := s.agent.Verification(presExID)
+	if !ok {
+		s.logger.Error(fmt.Sprintf(`no verification found for exchange %s`, presExID))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if ex, err := s.agent.PendingExchange(presExID); err == nil {
+		rec.ExpiresAt = &ex.ExpiresAt
+	}
+
+	s.writeJSON(rec, w)
+}
+
+func (s *Server) handleGetSelectionPolicy(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(s.agent.SelectionPolicy(), w)
+}
+
+func (s *Server) handleSetSelectionPolicy(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var req domain.SelectionPolicy
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = req.Validate(); err != nil {
+		s.logger.Error(fmt.Sprintf(`invalid selection policy - %v`, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = s.agent.SetSelectionPolicy(req); err != nil {
+		s.logger.Error(fmt.Sprintf(`set selection policy - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(req, w)
+}
+
+// handleGetCandidates lists the credentials which can be used for each referent of a received presentation request
+// so that the holder can choose them explicitly
+func (s *Server) handleGetCandidates(w http.ResponseWriter, r *http.Request) {
+	res, err := s.agent.Candidates(mux.Vars(r)[`pres_ex_id`])
+	if errors.Is(err, agent.ErrNoPresentationRecord) {
+		s.logger.Error(fmt.Sprintf(`get candidates - %v`, err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get candidates - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(res, w)
+}
+
+func (s *Server) handleGetAuditEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	entries, err := s.agent.AuditEntries(filter)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`get audit entries - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(entries, w)
+}
+
+func (s *Server) handleVerifyAuditLedger(w http.ResponseWriter, _ *http.Request) {
+	res, err := s.agent.VerifyAuditLedger()
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`verify audit ledger - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(res, w)
+}
+
+// handleExportAuditEntries writes the filtered audit entries either as JSON lines (default) or as CSV
+func (s *Server) handleExportAuditEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	entries, err := s.agent.AuditEntries(filter)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`export audit entries - %v`, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	switch r.URL.Query().Get(`format`) {
+	case `csv`:
+		cw := csv.NewWriter(buf)
+		_ = cw.Write([]string{`seq`, `cred_ex_id`, `connection_id`, `holder_label`, `cred_def_id`, `attributes`, `outcome`, `state`, `exchange_created_at`, `exchange_updated_at`, `recorded_at`, `prev_hash`, `hash`})
+		for _, e := range entries {
+			var attrs []string
+			for _, attr := range e.Attributes {
+				attrs = append(attrs, attr.Name+`:`+attr.Hash)
+			}
+			_ = cw.Write([]string{strconv.FormatInt(e.Seq, 10), e.CredExID, e.ConnectionID, e.HolderLabel, e.CredDefID,
+				strings.Join(attrs, `;`), e.Outcome, e.State, e.CreatedAt, e.UpdatedAt, e.RecordedAt.Format(time.RFC3339), e.PrevHash, e.Hash})
+		}
+		cw.Flush()
+		w.Header().Set(`Content-Type`, `text/csv`)
+		w.Header().Set(`Content-Disposition`, `attachment; filename="issuance-audit.csv"`)
+	case ``, `jsonl`:
+		enc := json.NewEncoder(buf)
+		for _, e := range entries {
+			_ = enc.Encode(e)
+		}
+		w.Header().Set(`Content-Type`, `application/x-ndjson`)
+		w.Header().Set(`Content-Disposition`, `attachment; filename="issuance-audit.jsonl"`)
+	default:
+		s.logger.Error(fmt.Sprintf(`unsupported export format %s`, r.URL.Query().Get(`format`)))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.writeResponse(buf.Bytes(), w)
+}
+
+func auditFilter(r *http.Request) (models.AuditFilter, error) {
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		CredExID:    q.Get(`cred_ex_id`),
+		HolderLabel: q.Get(`holder`),
+		CredDefID:   q.Get(`cred_def_id`),
+		Outcome:     q.Get(`outcome`),
+	}
+
+	var err error
+	if from := q.Get(`from`); from != `` {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return models.AuditFilter{}, fmt.Errorf(`invalid from timestamp - %v`, err)
+		}
+	}
+
+	if to := q.Get(`to`); to != `` {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return models.AuditFilter{}, fmt.Errorf(`invalid to timestamp - %v`, err)
+		}
+	}
+
+	return filter, nil
+}
+
+func (s *Server) writeJSON(v interface{}, w http.ResponseWriter) {
+	res, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`marshal response - %v`, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set(`Content-Type`, `application/json`)
 	s.writeResponse(res, w)
 }
 
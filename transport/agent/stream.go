@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YasiruR/agent/agent/events"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const streamKeepAlive = 15 * time.Second
+
+// handleEventStream streams events as Server-Sent Events. Clients resume a stream by sending the ID of the last
+// received event in Last-Event-ID header (or last_event_id query parameter).
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	filter, lastID, err := eventFilter(r)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.logger.Error(`event stream is not supported by the response writer`)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sub, backlog := s.events.Subscribe(filter, lastID)
+	defer s.events.Unsubscribe(sub)
+
+	w.Header().Set(`Content-Type`, `text/event-stream`)
+	w.Header().Set(`Cache-Control`, `no-cache`)
+	w.Header().Set(`Connection`, `keep-alive`)
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range backlog {
+		if err = writeSSE(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err = writeSSE(w, e); err != nil {
+				s.logger.Debug(fmt.Sprintf(`event stream closed - %v`, err))
+				return
+			}
+		case <-ticker.C:
+			if _, err = fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// handleEventSocket streams events as JSON text messages over a websocket with the same filters and resumption
+// as handleEventStream
+func (s *Server) handleEventSocket(w http.ResponseWriter, r *http.Request) {
+	filter, lastID, err := eventFilter(r)
+	if err != nil {
+		s.logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ws, err := s.upgradeWebsocket(w, r)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`websocket upgrade - %v`, err))
+		return
+	}
+	defer ws.Close()
+
+	sub, backlog := s.events.Subscribe(filter, lastID)
+	defer s.events.Unsubscribe(sub)
+
+	for _, e := range backlog {
+		if err = writeWS(ws, e); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err = writeWS(ws, e); err != nil {
+				s.logger.Debug(fmt.Sprintf(`event socket closed - %v`, err))
+				return
+			}
+		case <-ws.Closed():
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Topic, data)
+	return err
+}
+
+func writeWS(ws *wsConn, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return ws.WriteText(data)
+}
+
+// eventFilter parses comma separated topics and the connection ID from the query parameters along with the ID of
+// the last event received by the client
+func eventFilter(r *http.Request) (filter events.Filter, lastID int64, err error) {
+	q := r.URL.Query()
+	filter.ConnectionID = q.Get(`connection_id`)
+	if topics := q.Get(`topic`); topics != `` {
+		filter.Topics = make(map[string]bool)
+		for _, t := range strings.Split(topics, `,`) {
+			filter.Topics[strings.TrimSpace(t)] = true
+		}
+	}
+
+	last := r.Header.Get(`Last-Event-ID`)
+	if last == `` {
+		last = q.Get(`last_event_id`)
+	}
+
+	if last != `` {
+		if lastID, err = strconv.ParseInt(last, 10, 64); err != nil {
+			return events.Filter{}, 0, fmt.Errorf(`invalid last event ID %s - %v`, last, err)
+		}
+	}
+
+	return filter, lastID, nil
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wsWriteTimeout   = 10 * time.Second
+	wsMaxMessageSize = 64 * 1024
+)
+
+// wsConn wraps a websocket connection which only sends text messages to the client and consumes the control
+// messages sent by it, which is sufficient for streaming events
+type wsConn struct {
+	conn   *websocket.Conn
+	mu     *sync.Mutex
+	closed chan struct{}
+	once   *sync.Once
+}
+
+// upgradeWebsocket completes the opening handshake if the origin of the request is allowed. The upgrader responds
+// to the client itself when the handshake fails, hence the response writer should not be used afterwards.
+func (s *Server) upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	upgrader := websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	ws := &wsConn{conn: conn, mu: &sync.Mutex{}, closed: make(chan struct{}), once: &sync.Once{}}
+	go ws.readLoop()
+	return ws, nil
+}
+
+// checkOrigin accepts requests without an origin (non-browser clients), requests from the same host as the
+// controller and requests from the origins allowed explicitly
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get(`Origin`)
+	if origin == `` {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range s.origins {
+		if allowed == `*` || strings.EqualFold(strings.TrimSuffix(allowed, `/`), origin) {
+			return true
+		}
+	}
+
+	s.logger.Warn(fmt.Sprintf(`websocket connection rejected for origin %s`, origin))
+	return false
+}
+
+// Closed is closed once the client closes the connection or the connection fails
+func (c *wsConn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+func (c *wsConn) WriteText(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *wsConn) Close() {
+	c.mu.Lock()
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ``), time.Now().Add(wsWriteTimeout))
+	c.mu.Unlock()
+	c.shutdown()
+}
+
+func (c *wsConn) shutdown() {
+	c.once.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// readLoop consumes the messages sent by the client so that pings and close messages are handled by the default
+// handlers of the connection. Data messages from the client are ignored.
+func (c *wsConn) readLoop() {
+	defer c.shutdown()
+	for {
+		if _, _, err := c.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
@@ -1,9 +1,19 @@
 package requests
 
+import "github.com/YasiruR/agent/domain"
+
 type IssueCredentials struct {
-	AutoIssue    bool   `json:"auto_issue"`
-	AutoOffer    bool   `json:"auto_offer"`
-	AutoRemove   bool   `json:"auto_remove"`
+	AutoIssue  bool `json:"auto_issue"`
+	AutoOffer  bool `json:"auto_offer"`
+	AutoRemove bool `json:"auto_remove"`
+	ByFormat   struct {
+		CredOffer struct {
+			Indy struct {
+				CredDefID string `json:"cred_def_id"`
+				SchemaID  string `json:"schema_id"`
+			} `json:"indy"`
+		} `json:"cred_offer"`
+	} `json:"by_format"`
 	ConnID       string `json:"conn_id"`
 	CreatedAt    string `json:"created_at"`
 	CredExID     string `json:"cred_ex_id"`
@@ -52,14 +62,7 @@ type IssueCredentials struct {
 		} `json:"offers~attach"`
 		Thread struct{} `json:"~thread"`
 	} `json:"cred_offer"`
-	CredPreview struct {
-		Type       string `json:"@type"`
-		Attributes []struct {
-			Mime_type string `json:"mime-type"`
-			Name      string `json:"name"`
-			Value     string `json:"value"`
-		} `json:"attributes"`
-	} `json:"cred_preview"`
+	CredPreview  domain.CredentialPreview `json:"cred_preview"`
 	CredProposal struct {
 		ID                string `json:"@id"`
 		Type              string `json:"@type"`
@@ -96,6 +99,7 @@ type IssueCredentialsIndy struct {
 	CreatedAt           string `json:"created_at"`
 	CredExID            string `json:"cred_ex_id"`
 	CredExIndyID        string `json:"cred_ex_indy_id"`
+	CredIDStored        string `json:"cred_id_stored"`
 	CredRequestMetadata struct {
 		MasterSecretBlindingData struct {
 			VPrime  string      `json:"v_prime"`
@@ -107,3 +111,44 @@ type IssueCredentialsIndy struct {
 	RevRegID  string `json:"rev_reg_id"`
 	UpdatedAt string `json:"updated_at"`
 }
+
+type IssuerCredRev struct {
+	CreatedAt string `json:"created_at"`
+	CredDefID string `json:"cred_def_id"`
+	CredExID  string `json:"cred_ex_id"`
+	CredRevID string `json:"cred_rev_id"`
+	RecordID  string `json:"record_id"`
+	RevRegID  string `json:"rev_reg_id"`
+	State     string `json:"state"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// IssueCredentialsV1 is the credential exchange record of issue-credential v1.0
+type IssueCredentialsV1 struct {
+	AutoIssue              bool   `json:"auto_issue"`
+	AutoOffer              bool   `json:"auto_offer"`
+	AutoRemove             bool   `json:"auto_remove"`
+	ConnectionID           string `json:"connection_id"`
+	CreatedAt              string `json:"created_at"`
+	CredentialDefinitionID string `json:"credential_definition_id"`
+	CredentialExchangeID   string `json:"credential_exchange_id"`
+	CredentialID           string `json:"credential_id"`
+	CredentialOfferDict    struct {
+		ID                string                   `json:"@id"`
+		Type              string                   `json:"@type"`
+		Comment           string                   `json:"comment"`
+		CredentialPreview domain.CredentialPreview `json:"credential_preview"`
+	} `json:"credential_offer_dict"`
+	CredentialProposalDict struct {
+		ID                 string                   `json:"@id"`
+		Type               string                   `json:"@type"`
+		Comment            string                   `json:"comment"`
+		CredentialProposal domain.CredentialPreview `json:"credential_proposal"`
+	} `json:"credential_proposal_dict"`
+	Initiator string `json:"initiator"`
+	Role      string `json:"role"`
+	State     string `json:"state"`
+	ThreadID  string `json:"thread_id"`
+	Trace     bool   `json:"trace"`
+	UpdatedAt string `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package requests
+
+type DiscoverFeature struct {
+	ConnectionID        string `json:"connection_id"`
+	CreatedAt           string `json:"created_at"`
+	DiscoveryExchangeID string `json:"discovery_exchange_id"`
+	Disclose            struct {
+		ID        string `json:"@id"`
+		Type      string `json:"@type"`
+		Protocols []struct {
+			Pid   string   `json:"pid"`
+			Roles []string `json:"roles"`
+		} `json:"protocols"`
+	} `json:"disclose"`
+	UpdatedAt string `json:"updated_at"`
+}
@@ -0,0 +1,9 @@
+package requests
+
+type BasicMessage struct {
+	ConnectionID string `json:"connection_id"`
+	Content      string `json:"content"`
+	MessageID    string `json:"message_id"`
+	SentTime     string `json:"sent_time"`
+	State        string `json:"state"`
+}
@@ -34,3 +34,24 @@ type PresentationProof struct {
 	Trace     bool   `json:"trace"`
 	UpdatedAt string `json:"updated_at"`
 }
+
+// PresentationProofV1 is the presentation exchange record of present-proof v1.0
+type PresentationProofV1 struct {
+	AutoPresent             bool                    `json:"auto_present"`
+	ConnectionID            string                  `json:"connection_id"`
+	CreatedAt               string                  `json:"created_at"`
+	Initiator               string                  `json:"initiator"`
+	PresentationExchangeID  string                  `json:"presentation_exchange_id"`
+	PresentationRequest     domain.IndyProofRequest `json:"presentation_request"`
+	PresentationRequestDict struct {
+		ID      string `json:"@id"`
+		Type    string `json:"@type"`
+		Comment string `json:"comment"`
+	} `json:"presentation_request_dict"`
+	Role      string `json:"role"`
+	State     string `json:"state"`
+	ThreadID  string `json:"thread_id"`
+	Trace     bool   `json:"trace"`
+	UpdatedAt string `json:"updated_at"`
+	Verified  string `json:"verified"`
+}
@@ -0,0 +1,22 @@
+package requests
+
+type EndorseTransaction struct {
+	ConnectionID   string `json:"connection_id"`
+	CreatedAt      string `json:"created_at"`
+	MessagesAttach []struct {
+		ID       string `json:"@id"`
+		MimeType string `json:"mime-type"`
+		Data     struct {
+			JSON interface{} `json:"json"`
+		} `json:"data"`
+	} `json:"messages_attach"`
+	SignatureRequest []struct {
+		AuthorGoalCode string `json:"author_goal_code"`
+		SignatureType  string `json:"signature_type"`
+		SignerGoalCode string `json:"signer_goal_code"`
+	} `json:"signature_request"`
+	State         string `json:"state"`
+	ThreadID      string `json:"thread_id"`
+	TransactionID string `json:"transaction_id"`
+	UpdatedAt     string `json:"updated_at"`
+}
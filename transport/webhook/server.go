@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/YasiruR/agent/agent"
+	"github.com/YasiruR/agent/agent/events"
+	"github.com/YasiruR/agent/agent/models"
+	"github.com/YasiruR/agent/domain"
 	"github.com/YasiruR/agent/transport/webhook/requests"
 	"github.com/gorilla/mux"
 	"github.com/tryfix/log"
@@ -16,19 +19,32 @@ type Server struct {
 	port   int
 	router *mux.Router
 	agent  *agent.Agent
+	events *events.Broker
 	logger log.Logger
 }
 
-func New(port int, agent *agent.Agent, logger log.Logger) *Server {
-	return &Server{port: port, router: mux.NewRouter(), agent: agent, logger: logger}
-}
-
-func (s *Server) Serve() {
+func New(port int, agent *agent.Agent, broker *events.Broker, logger log.Logger) *Server {
+	s := &Server{port: port, router: mux.NewRouter(), agent: agent, events: broker, logger: logger}
 	s.router.HandleFunc(`/topic/connections/`, s.handleConnections).Methods(http.MethodPost)
 	s.router.HandleFunc(`/topic/issue_credential_v2_0/`, s.handleCredentials).Methods(http.MethodPost)
 	s.router.HandleFunc(`/topic/issue_credential_v2_0_indy/`, s.handleIndyCredentials).Methods(http.MethodPost)
+	s.router.HandleFunc(`/topic/issue_credential/`, s.handleCredentialsV1).Methods(http.MethodPost)
+	s.router.HandleFunc(`/topic/issuer_cred_rev/`, s.handleCredentialRevocation).Methods(http.MethodPost)
 	s.router.HandleFunc(`/topic/present_proof_v2_0/`, s.handlePresentProof).Methods(http.MethodPost)
+	s.router.HandleFunc(`/topic/present_proof/`, s.handlePresentProofV1).Methods(http.MethodPost)
+	s.router.HandleFunc(`/topic/discover_feature/`, s.handleDiscoverFeature).Methods(http.MethodPost)
+	s.router.HandleFunc(`/topic/endorse_transaction/`, s.handleEndorseTransaction).Methods(http.MethodPost)
+	s.router.HandleFunc(`/topic/basicmessages/`, s.handleBasicMessage).Methods(http.MethodPost)
+
+	return s
+}
+
+// Handler returns the router of the webhook endpoints so that the server can also be mounted elsewhere (eg: httptest)
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
 
+func (s *Server) Serve() {
 	s.logger.Info(fmt.Sprintf("webhook server started listening on %d", s.port))
 	if err := http.ListenAndServe(":"+strconv.Itoa(s.port), s.router); err != nil {
 		s.logger.Fatal(err)
@@ -51,6 +67,7 @@ func (s *Server) handleConnections(_ http.ResponseWriter, r *http.Request) {
 
 	s.logger.Debug("webhook received for connection", req)
 	s.agent.AddConnection(req.TheirLabel, req.ConnectionID)
+	s.events.Publish(events.Event{Topic: events.TopicConnection, ConnectionID: req.ConnectionID, Label: req.TheirLabel, Role: req.TheirRole, State: req.State})
 }
 
 func (s *Server) handleCredentials(_ http.ResponseWriter, r *http.Request) {
@@ -68,10 +85,127 @@ func (s *Server) handleCredentials(_ http.ResponseWriter, r *http.Request) {
 	}
 
 	s.logger.Debug("webhook received for credentials", req)
+	s.publishExchange(events.TopicCredential, req.ConnID, req.CredExID, req.Role, req.State)
+	if req.State == `deleted` {
+		s.agent.RemoveCredentialRecord(req.CredExID)
+		return
+	}
 
 	// workaround to proceed with credential offers
-	if req.CredIssue.ID == `` && req.CredOffer.ID != `` {
-		s.agent.AddCredentialRecord(req.CredOffer.Comment, req.CredExID)
+	if req.Role == `holder` && req.CredIssue.ID == `` && req.CredOffer.ID != `` {
+		// offers constructed from templates may carry a custom comment, hence the label is resolved by the
+		// connection first and the comment is only used as the fallback
+		label, err := s.agent.GetLabelByConnection(req.ConnID)
+		if err != nil {
+			label = req.CredOffer.Comment
+		}
+		s.agent.AddCredentialRecord(label, req.CredExID)
+	}
+	s.agent.UpdateExchangeState(models.ExchangeTypeCredential, req.CredExID, req.State)
+	s.agent.UpdateConnectionlessExchange(req.ThreadID, req.CredExID, req.ConnID, req.State)
+
+	if req.Role == `issuer` {
+		err = s.agent.RecordIssuance(models.IssuanceEvent{
+			CredExID:     req.CredExID,
+			ConnectionID: req.ConnID,
+			CredDefID:    req.ByFormat.CredOffer.Indy.CredDefID,
+			State:        req.State,
+			Attributes:   req.CredPreview.Attributes,
+			CreatedAt:    req.CreatedAt,
+			UpdatedAt:    req.UpdatedAt,
+		})
+		if err != nil {
+			s.logger.Error(fmt.Sprintf(`record issuance - %v`, err))
+		}
+	}
+}
+
+// handleCredentialsV1 processes credential exchanges of issue-credential v1.0 similar to handleCredentials after
+// normalizing the states to v2.0
+func (s *Server) handleCredentialsV1(_ http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	var req requests.IssueCredentialsV1
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.logger.Debug("webhook received for credentials v1.0", req)
+	state := agent.NormalizeState(agent.ProtocolV1, req.State)
+	s.publishExchange(events.TopicCredential, req.ConnectionID, req.CredentialExchangeID, req.Role, state)
+	if state == `deleted` {
+		s.agent.RemoveCredentialRecord(req.CredentialExchangeID)
+		return
+	}
+	s.agent.SetExchangeVersion(req.CredentialExchangeID, agent.ProtocolV1)
+
+	if req.Role == `holder` && state == `offer-received` {
+		label, err := s.agent.GetLabelByConnection(req.ConnectionID)
+		if err != nil {
+			label = req.CredentialOfferDict.Comment
+		}
+		s.agent.AddCredentialRecord(label, req.CredentialExchangeID)
+	}
+	s.agent.UpdateExchangeState(models.ExchangeTypeCredential, req.CredentialExchangeID, state)
+	if req.Role == `holder` && req.CredentialID != `` {
+		s.agent.RecordStoredCredential(req.CredentialID)
+	}
+
+	if req.Role == `issuer` {
+		attrs := req.CredentialOfferDict.CredentialPreview.Attributes
+		if len(attrs) == 0 {
+			attrs = req.CredentialProposalDict.CredentialProposal.Attributes
+		}
+
+		err = s.agent.RecordIssuance(models.IssuanceEvent{
+			CredExID:     req.CredentialExchangeID,
+			ConnectionID: req.ConnectionID,
+			CredDefID:    req.CredentialDefinitionID,
+			State:        state,
+			Attributes:   attrs,
+			CreatedAt:    req.CreatedAt,
+			UpdatedAt:    req.UpdatedAt,
+		})
+		if err != nil {
+			s.logger.Error(fmt.Sprintf(`record issuance - %v`, err))
+		}
+	}
+}
+
+func (s *Server) handleCredentialRevocation(_ http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	var req requests.IssuerCredRev
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.logger.Debug("webhook received for credential revocation", req)
+	if req.State != `revoked` {
+		return
+	}
+
+	err = s.agent.RecordIssuance(models.IssuanceEvent{
+		CredExID:  req.CredExID,
+		CredDefID: req.CredDefID,
+		State:     `credential-revoked`,
+		CreatedAt: req.CreatedAt,
+		UpdatedAt: req.UpdatedAt,
+	})
+	if err != nil {
+		s.logger.Error(fmt.Sprintf(`record revocation - %v`, err))
 	}
 }
 
@@ -90,6 +224,9 @@ func (s *Server) handleIndyCredentials(_ http.ResponseWriter, r *http.Request) {
 	}
 
 	s.logger.Debug("webhook received for credentials for indy", req)
+	if req.CredIDStored != `` {
+		s.agent.RecordStoredCredential(req.CredIDStored)
+	}
 }
 
 func (s *Server) handlePresentProof(_ http.ResponseWriter, r *http.Request) {
@@ -107,5 +244,119 @@ func (s *Server) handlePresentProof(_ http.ResponseWriter, r *http.Request) {
 	}
 
 	s.logger.Debug("webhook received for proof presentation", req)
+	s.publishExchange(events.TopicProof, req.ConnectionID, req.PresExID, req.Role, req.State)
+	if req.State == `deleted` {
+		s.agent.RemovePresentationRecord(req.PresExID)
+		return
+	}
+
 	s.agent.AddPresentationRecord(req.PresRequest.Comment, req.PresExID, req.ByFormat.PresRequest)
+	s.agent.UpdateExchangeState(models.ExchangeTypeProof, req.PresExID, req.State)
+	s.agent.UpdateConnectionlessExchange(req.ThreadID, req.PresExID, req.ConnectionID, req.State)
+	if req.Role == `verifier` && req.State == `presentation-received` {
+		go s.agent.AutoVerify(req.PresExID)
+	}
+}
+
+// handlePresentProofV1 processes presentation exchanges of present-proof v1.0 similar to handlePresentProof after
+// normalizing the states to v2.0
+func (s *Server) handlePresentProofV1(_ http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	var req requests.PresentationProofV1
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.logger.Debug("webhook received for proof presentation v1.0", req)
+	state := agent.NormalizeState(agent.ProtocolV1, req.State)
+	s.publishExchange(events.TopicProof, req.ConnectionID, req.PresentationExchangeID, req.Role, state)
+	if state == `deleted` {
+		s.agent.RemovePresentationRecord(req.PresentationExchangeID)
+		return
+	}
+	s.agent.SetExchangeVersion(req.PresentationExchangeID, agent.ProtocolV1)
+
+	s.agent.AddPresentationRecord(req.PresentationRequestDict.Comment, req.PresentationExchangeID, domain.PresentationRequest{Indy: &req.PresentationRequest})
+	s.agent.UpdateExchangeState(models.ExchangeTypeProof, req.PresentationExchangeID, state)
+	if req.Role == `verifier` && state == `presentation-received` {
+		go s.agent.AutoVerify(req.PresentationExchangeID)
+	}
+}
+
+// handleDiscoverFeature chooses the protocol versions of the connection from the features disclosed by the peer
+func (s *Server) handleDiscoverFeature(_ http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	var req requests.DiscoverFeature
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.logger.Debug("webhook received for discovered features", req)
+	var pids []string
+	for _, p := range req.Disclose.Protocols {
+		pids = append(pids, p.Pid)
+	}
+	s.agent.SetDiscoveredProtocols(req.ConnectionID, pids)
+}
+
+func (s *Server) handleBasicMessage(_ http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	var req requests.BasicMessage
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.logger.Debug("webhook received for basic message", req)
+	label, _ := s.agent.GetLabelByConnection(req.ConnectionID)
+	s.events.Publish(events.Event{Topic: events.TopicMessage, ConnectionID: req.ConnectionID, Label: label, ExchangeID: req.MessageID, State: req.State, Content: req.Content})
+}
+
+func (s *Server) handleEndorseTransaction(_ http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	var req requests.EndorseTransaction
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.logger.Debug("webhook received for endorse transaction", req.TransactionID, req.State)
+	if req.State == models.TxnStateRequestReceived {
+		go s.agent.HandleEndorsementRequest(req.TransactionID)
+		return
+	}
+
+	s.agent.UpdateTransaction(req.TransactionID, req.State)
+}
+
+// publishExchange streams the state change of a credential or proof exchange to the controller clients
+func (s *Server) publishExchange(topic, connID, exID, role, state string) {
+	label, _ := s.agent.GetLabelByConnection(connID)
+	s.events.Publish(events.Event{Topic: topic, ConnectionID: connID, Label: label, ExchangeID: exID, Role: role, State: state})
 }